@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Классы символов в обозначениях масок hashcat
+var charClasses = []struct {
+	mask string
+	name string
+	size int64
+}{
+	{"?l", "строчные", 26},
+	{"?u", "заглавные", 26},
+	{"?d", "цифры", 10},
+	{"?s", "спецсимволы", 33},
+}
+
+// Класс символа: 0 — строчная, 1 — заглавная, 2 — цифра, 3 — остальное
+func charClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 0
+	case r >= 'A' && r <= 'Z':
+		return 1
+	case r >= '0' && r <= '9':
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Маска пароля, например ?l?l?l?l?d
+func passwordMask(password string) string {
+	var sb strings.Builder
+	for _, r := range password {
+		sb.WriteString(charClasses[charClass(r)].mask)
+	}
+	return sb.String()
+}
+
+// Размер пространства ключей для маски пароля (в float64, так как длинные маски переполняют int64)
+func maskKeyspace(password string) float64 {
+	size := 1.0
+	for _, r := range password {
+		size *= float64(charClasses[charClass(r)].size)
+	}
+	return size
+}
+
+// Состав пароля по классам символов, например "строчные+цифры"
+func passwordComposition(password string) string {
+	var present [4]bool
+	for _, r := range password {
+		present[charClass(r)] = true
+	}
+	var parts []string
+	for i, ok := range present {
+		if ok {
+			parts = append(parts, charClasses[i].name)
+		}
+	}
+	if len(parts) == 0 {
+		return "пустой"
+	}
+	return strings.Join(parts, "+")
+}
+
+// Базовое слово: самая длинная последовательность букв в нижнем регистре
+func baseWord(password string) string {
+	var best, cur []rune
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			cur = append(cur, unicode.ToLower(r))
+			if len(cur) > len(best) {
+				best = append(best[:0], cur...)
+			}
+			continue
+		}
+		cur = cur[:0]
+	}
+	return string(best)
+}
+
+// Проверка, что строка — шестнадцатеричный хэш MD5 или SHA-256
+func isHexHash(s string) bool {
+	if len(s) != 32 && len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Извлечение хэша и пароля из строки potfile ("хэш:пароль") или строки
+// итогов перебора ("  хэш: пароль"); ok == false для прочих строк
+func parseCrackedLine(line string) (hash, plain string, ok bool) {
+	if rest, found := strings.CutPrefix(line, "  "); found {
+		if hash, plain, found := strings.Cut(rest, ": "); found && isHexHash(hash) {
+			return hash, plain, true
+		}
+	}
+	if hash, plain, found := strings.Cut(line, ":"); found && isHexHash(hash) {
+		return hash, plain, true
+	}
+	return "", "", false
+}
+
+// Чтение взломанных паролей из potfile или вывода с итогами перебора.
+// Если в файле нет ни одной строки с хэшем, он считается обычным списком паролей.
+func readCracked(r io.Reader) ([]string, error) {
+	var passwords, plainLines []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		hash, plain, ok := parseCrackedLine(line)
+		if !ok {
+			plainLines = append(plainLines, line)
+			continue
+		}
+		if _, dup := seen[hash]; dup {
+			continue
+		}
+		seen[hash] = struct{}{}
+		passwords = append(passwords, plain)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(seen) == 0 {
+		return plainLines, nil
+	}
+	return passwords, nil
+}
+
+// Запись в potfile новых найденных паролей
+func appendPotfile(path string, found map[string]string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for hash, password := range found {
+		if _, err := fmt.Fprintf(file, "%s:%s\n", hash, password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type countEntry struct {
+	key   string
+	count int
+}
+
+// Сортировка счётчиков по убыванию, при равенстве — по ключу
+func sortedCounts(counts map[string]int) []countEntry {
+	entries := make([]countEntry, 0, len(counts))
+	for k, c := range counts {
+		entries = append(entries, countEntry{k, c})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// Отчёт по взломанным паролям для выработки парольной политики
+func printAudit(w io.Writer, passwords []string, top int) {
+	total := len(passwords)
+	fmt.Fprintf(w, "=== Анализ взломанных паролей (%d шт.) ===\n", total)
+	if total == 0 {
+		return
+	}
+
+	lengths := make(map[int]int)
+	compositions := make(map[string]int)
+	words := make(map[string]int)
+	masks := make(map[string]int)
+	maskSizes := make(map[string]float64)
+	for _, p := range passwords {
+		lengths[len([]rune(p))]++
+		compositions[passwordComposition(p)]++
+		if word := baseWord(p); word != "" {
+			words[word]++
+		}
+		m := passwordMask(p)
+		masks[m]++
+		maskSizes[m] = maskKeyspace(p)
+	}
+
+	fmt.Fprintln(w, "\nРаспределение по длине:")
+	lens := make([]int, 0, len(lengths))
+	for l := range lengths {
+		lens = append(lens, l)
+	}
+	sort.Ints(lens)
+	for _, l := range lens {
+		fmt.Fprintf(w, "  %3d: %6d (%5.1f%%)\n", l, lengths[l], percent(lengths[l], total))
+	}
+
+	fmt.Fprintln(w, "\nСостав по классам символов:")
+	for _, e := range sortedCounts(compositions) {
+		fmt.Fprintf(w, "  %-40s %6d (%5.1f%%)\n", e.key, e.count, percent(e.count, total))
+	}
+
+	fmt.Fprintln(w, "\nЧастые базовые слова:")
+	for i, e := range sortedCounts(words) {
+		if i == top {
+			break
+		}
+		fmt.Fprintf(w, "  %-20s %6d\n", e.key, e.count)
+	}
+
+	// Покрытие: какая доля паролей попадает в первые N масок и сколько кандидатов надо перебрать
+	fmt.Fprintln(w, "\nЧастые маски и покрытие пространства ключей:")
+	fmt.Fprintf(w, "  %-32s %6s %8s %10s %22s\n", "маска", "кол-во", "доля", "покрытие", "кандидатов всего")
+	covered := 0
+	var keyspaceSum float64
+	for i, e := range sortedCounts(masks) {
+		if i == top {
+			break
+		}
+		covered += e.count
+		keyspaceSum += maskSizes[e.key]
+		fmt.Fprintf(w, "  %-32s %6d %7.1f%% %9.1f%% %22.4g\n", e.key, e.count, percent(e.count, total), percent(covered, total), keyspaceSum)
+	}
+	fmt.Fprintf(w, "  Всего различных масок: %d\n", len(masks))
+}
+
+// Режим анализа: чтение файла со взломанными паролями и вывод отчёта
+func runAudit(path string, top int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	passwords, err := readCracked(file)
+	if err != nil {
+		return err
+	}
+	printAudit(os.Stdout, passwords, top)
+	return nil
+}
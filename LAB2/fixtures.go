@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Генерация count различных паролей из src и их хэшей.
+// Алгоритмы из algos назначаются паролям по очереди.
+// При одинаковом seed результат всегда один и тот же.
+func generateFixtures(src candidateSource, count int, algos []string, seed uint64) (map[string]string, error) {
+	if int64(count) > src.count() {
+		return nil, fmt.Errorf("в источнике %s только %d кандидатов, нельзя выбрать %d", src, src.count(), count)
+	}
+	for _, algo := range algos {
+		if hashAlgorithms[algo] == nil {
+			return nil, fmt.Errorf("неизвестный алгоритм %q", algo)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	answers := make(map[string]string, count)
+	used := make(map[int64]struct{}, count)
+	for len(answers) < count {
+		idx := rng.Int64N(src.count())
+		if _, dup := used[idx]; dup {
+			continue
+		}
+		used[idx] = struct{}{}
+		plain := src.at(idx)
+		algo := algos[len(answers)%len(algos)]
+		answers[hashAlgorithms[algo](plain)] = plain
+	}
+	return answers, nil
+}
+
+// Запись hashes.txt (только хэши) и answers.txt (хэш:пароль, как в potfile)
+func writeFixtures(dir string, answers map[string]string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(answers))
+	for hash := range answers {
+		keys = append(keys, hash)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, hash := range keys {
+		pairs[i] = hash + ":" + answers[hash]
+	}
+	if err := writeCandidates(filepath.Join(dir, "hashes.txt"), keys); err != nil {
+		return err
+	}
+	return writeCandidates(filepath.Join(dir, "answers.txt"), pairs)
+}
+
+// Режим генерации тестовых хэшей
+func runFixtureGenerator(src candidateSource, count int, algos []string, seed uint64, outDir string) error {
+	answers, err := generateFixtures(src, count, algos, seed)
+	if err != nil {
+		return err
+	}
+	if err := writeFixtures(outDir, answers); err != nil {
+		return err
+	}
+	fmt.Printf("Сгенерировано %d хэшей (%s) в каталоге %s\n", len(answers), src, outDir)
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// Сравнение найденных паролей с ответами
+func compareFound(found, answers map[string]string) error {
+	for hash, plain := range answers {
+		got, ok := found[hash]
+		if !ok {
+			return fmt.Errorf("не найден пароль %q (%s)", plain, hash)
+		}
+		if got != plain {
+			return fmt.Errorf("для %s найден %q вместо %q", hash, got, plain)
+		}
+	}
+	if len(found) != len(answers) {
+		return fmt.Errorf("найдено %d паролей вместо %d", len(found), len(answers))
+	}
+	return nil
+}
+
+// Перебор с остановкой на середине, сохранением контрольной точки и продолжением
+func runWithCheckpoint(t *testing.T, hashes map[string]struct{}, numThreads int, src candidateSource, path string) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+	first := bruteForceMultiThread(ctx, io.Discard, hashes, numThreads, src, runLimits{MaxCandidates: src.count() / 2}, 0)
+	if err := saveCheckpoint(path, checkpoint{Offset: first.offset, Total: src.count(), Source: src.String(), Hashes: hashesFingerprint(hashes), Found: first.found}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Hashes != hashesFingerprint(hashes) {
+		t.Fatal("отпечаток хэшей в контрольной точке не совпадает")
+	}
+	second := bruteForceMultiThread(ctx, io.Discard, hashes, numThreads, src, runLimits{}, cp.Offset)
+	for hash, password := range second.found {
+		cp.Found[hash] = password
+	}
+	return cp.Found
+}
+
+// Источники кандидатов для проверки: маска и словарь из случайных слов
+func fixtureSources(t *testing.T, dir string) []candidateSource {
+	t.Helper()
+	mask, err := parseMask("?l?d?l?l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordMask, err := parseMask("?u?l?l?l?d?d?s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, err := generateFixtures(wordMask, 2000, []string{"md5"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordsPath := filepath.Join(dir, "words.txt")
+	if err := writeCandidates(wordsPath, slices.Sorted(maps.Values(words))); err != nil {
+		t.Fatal(err)
+	}
+	wl, err := loadWordlist(wordsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []candidateSource{mask, wl}
+}
+
+// Для маски и для словаря генерируются тестовые хэши, после чего перебор
+// должен найти все ответы при любом количестве потоков, а также при
+// остановке и продолжении с контрольной точки
+func TestBruteForceFixtures(t *testing.T) {
+	dir := t.TempDir()
+	threadCounts := []int{1, 2, 3}
+	if n := runtime.NumCPU(); n > 3 {
+		threadCounts = append(threadCounts, n)
+	}
+
+	for i, src := range fixtureSources(t, dir) {
+		answers, err := generateFixtures(src, 25, []string{"md5", "sha256"}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixtureDir := filepath.Join(dir, fmt.Sprint(i))
+		if err := writeFixtures(fixtureDir, answers); err != nil {
+			t.Fatal(err)
+		}
+		hashes, err := loadHashes(filepath.Join(fixtureDir, "hashes.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, n := range threadCounts {
+			t.Run(fmt.Sprintf("%s, потоков %d", src, n), func(t *testing.T) {
+				res := bruteForceMultiThread(context.Background(), io.Discard, hashes, n, src, runLimits{}, 0)
+				if err := compareFound(res.found, answers); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+		t.Run(fmt.Sprintf("%s, с контрольной точкой", src), func(t *testing.T) {
+			found := runWithCheckpoint(t, hashes, threadCounts[len(threadCounts)-1], src, filepath.Join(fixtureDir, "checkpoint"))
+			if err := compareFound(found, answers); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Одинаковый seed даёт одинаковые тестовые хэши
+func TestGenerateFixturesDeterministic(t *testing.T) {
+	mask, err := parseMask("?d?d?d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := generateFixtures(mask, 10, []string{"md5", "sha256"}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := generateFixtures(mask, 10, []string{"md5", "sha256"}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(a, b) {
+		t.Error("при одинаковом seed получены разные хэши")
+	}
+	if _, err := generateFixtures(mask, 1001, []string{"md5"}, 7); err == nil {
+		t.Error("ожидалась ошибка: в маске ?d?d?d только 1000 кандидатов")
+	}
+}
+
+// Отпечаток не зависит от порядка хэшей и меняется вместе со списком
+func TestHashesFingerprint(t *testing.T) {
+	set := func(hashes ...string) map[string]struct{} {
+		m := make(map[string]struct{})
+		for _, h := range hashes {
+			m[h] = struct{}{}
+		}
+		return m
+	}
+	base := hashesFingerprint(set("a1", "b2", "c3"))
+	for _, c := range []struct {
+		name   string
+		hashes map[string]struct{}
+		same   bool
+	}{
+		{"тот же список", set("c3", "a1", "b2"), true},
+		{"другой хэш", set("a1", "b2", "d4"), false},
+		{"лишний хэш", set("a1", "b2", "c3", "d4"), false},
+		{"склейка соседних хэшей", set("a1b2", "c3"), false},
+		{"пустой список", set(), false},
+	} {
+		if got := hashesFingerprint(c.hashes) == base; got != c.same {
+			t.Errorf("%s: совпадение %v, ожидалось %v", c.name, got, c.same)
+		}
+	}
+}
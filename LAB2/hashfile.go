@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Хэши из задания, используются, если файл с хэшами не указан
+var readmeHashes = []string{
+	"1115dd800feaacefdf481f1f9070374a2a81e27880f187396db67958b207cbad",
+	"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b",
+	"74e1bb62f8dabb8125a58852b63bdf6eaef667cb56ac7f7cdba6d7305c50a22f",
+	"7a68f09bd992671bb3b19a5e70b7827e",
+}
+
+// Поддерживаемые алгоритмы хэширования
+var hashAlgorithms = map[string]func(string) string{
+	"md5":    md5Hash,
+	"sha256": sha256Hash,
+}
+
+// Чтение хэшей, по одному в строке. Пустые строки и строки с # пропускаются.
+func readHashes(r io.Reader) (map[string]struct{}, error) {
+	hashes := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		hash := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if hash == "" || strings.HasPrefix(hash, "#") {
+			continue
+		}
+		if !isHexHash(hash) {
+			return nil, fmt.Errorf("строка %d: %q не является хэшем MD5 или SHA-256", line, hash)
+		}
+		hashes[hash] = struct{}{}
+	}
+	return hashes, scanner.Err()
+}
+
+// Загрузка хэшей из файла; при пустом пути возвращаются хэши из задания
+func loadHashes(path string) (map[string]struct{}, error) {
+	if path == "" {
+		hashes := make(map[string]struct{}, len(readmeHashes))
+		for _, hash := range readmeHashes {
+			hashes[hash] = struct{}{}
+		}
+		return hashes, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readHashes(file)
+}
+
+// Именованный список хэшей, например дамп одной системы или отдела
+type hashList struct {
+	name   string
+	hashes map[string]struct{}
+}
+
+// Загрузка списков хэшей из файлов, перечисленных через запятую.
+// Без файлов возвращается один список с хэшами из задания.
+func loadHashLists(paths string) ([]hashList, error) {
+	if paths == "" {
+		hashes, err := loadHashes("")
+		return []hashList{{name: "задание", hashes: hashes}}, err
+	}
+	var lists []hashList
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		hashes, err := loadHashes(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		lists = append(lists, hashList{name: path, hashes: hashes})
+	}
+	return lists, nil
+}
+
+// Объединение списков в один набор для проверки кандидатов
+func mergeHashLists(lists []hashList) map[string]struct{} {
+	merged := make(map[string]struct{})
+	for _, list := range lists {
+		for hash := range list.hashes {
+			merged[hash] = struct{}{}
+		}
+	}
+	return merged
+}
+
+// Итоги по каждому списку: доля взломанных хэшей, время последней находки
+// в этом запуске и найденные пароли. Хэш, входящий в несколько списков,
+// засчитывается каждому из них.
+func printListSummary(lists []hashList, found map[string]string, foundAt map[string]time.Duration) {
+	fmt.Println("Итоги по спискам:")
+	for _, list := range lists {
+		var cracked []string
+		var last time.Duration
+		for hash := range list.hashes {
+			if _, ok := found[hash]; !ok {
+				continue
+			}
+			cracked = append(cracked, hash)
+			last = max(last, foundAt[hash])
+		}
+		sort.Strings(cracked)
+
+		fmt.Printf("  %s: найдено %d из %d (%.1f%%)", list.name, len(cracked), len(list.hashes), percent(len(cracked), len(list.hashes)))
+		if last > 0 {
+			fmt.Printf(", последняя находка через %s", last.Round(time.Millisecond))
+		}
+		if len(cracked) == len(list.hashes) {
+			fmt.Print(", список взломан полностью")
+		}
+		fmt.Println()
+		for _, hash := range cracked {
+			fmt.Printf("    %s: %s\n", hash, found[hash])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Наборы символов для масок в обозначениях hashcat
+const (
+	lowerLetters = "abcdefghijklmnopqrstuvwxyz"
+	upperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits       = "0123456789"
+	specials     = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// Маска по умолчанию: пятибуквенные пароли из задания
+const defaultMask = "?l?l?l?l?l"
+
+// Источник кандидатов, пронумерованных от 0 до count()-1.
+// Нумерация нужна, чтобы потоки делили работу на диапазоны,
+// а контрольная точка хранила одно число.
+type candidateSource interface {
+	count() int64
+	at(index int64) string
+	String() string // описание источника для контрольной точки
+}
+
+// Пространство ключей: для каждой позиции пароля свой набор символов.
+// Кандидаты нумеруются от 0 до size-1, последняя позиция меняется быстрее всех,
+// поэтому порядок перебора совпадает с вложенными циклами aaaaa, aaaab, ...
+type keyspace struct {
+	mask     string
+	charsets []string
+	size     int64
+}
+
+// Разбор маски: ?l ?u ?d ?s ?a — наборы символов, ?? — знак вопроса,
+// остальные символы подставляются как есть
+func parseMask(mask string) (keyspace, error) {
+	ks := keyspace{mask: mask, size: 1}
+	for i := 0; i < len(mask); i++ {
+		cs := mask[i : i+1]
+		if mask[i] == '?' {
+			if i+1 == len(mask) {
+				return ks, fmt.Errorf("маска %q: незавершённый символ ?", mask)
+			}
+			i++
+			switch mask[i] {
+			case 'l':
+				cs = lowerLetters
+			case 'u':
+				cs = upperLetters
+			case 'd':
+				cs = digits
+			case 's':
+				cs = specials
+			case 'a':
+				cs = lowerLetters + upperLetters + digits + specials
+			case '?':
+				cs = "?"
+			default:
+				return ks, fmt.Errorf("маска %q: неизвестный набор ?%c", mask, mask[i])
+			}
+		}
+		if ks.size > math.MaxInt64/int64(len(cs)) {
+			return ks, fmt.Errorf("маска %q: слишком большое пространство ключей", mask)
+		}
+		ks.size *= int64(len(cs))
+		ks.charsets = append(ks.charsets, cs)
+	}
+	if len(ks.charsets) == 0 {
+		return ks, fmt.Errorf("пустая маска")
+	}
+	return ks, nil
+}
+
+func (k keyspace) count() int64 {
+	return k.size
+}
+
+// Получение кандидата по его номеру
+func (k keyspace) at(index int64) string {
+	buf := make([]byte, len(k.charsets))
+	for pos := len(k.charsets) - 1; pos >= 0; pos-- {
+		cs := k.charsets[pos]
+		buf[pos] = cs[index%int64(len(cs))]
+		index /= int64(len(cs))
+	}
+	return string(buf)
+}
+
+func (k keyspace) String() string {
+	return "mask " + k.mask
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Ограничения на запуск перебора. Нулевое значение поля означает отсутствие ограничения.
+type runLimits struct {
+	MaxRuntime    time.Duration // максимальное время работы
+	MaxCandidates int64         // максимальное количество проверенных кандидатов
+	Rate          float64       // ограничение скорости, хэшей в секунду
+	CPUPercent    int           // целевая загрузка процессора каждым потоком, 1–100
+}
+
+// Размер порции кандидатов, которую поток забирает за один раз.
+// При ограничении скорости порция уменьшается, чтобы ожидание было равномерным.
+func (l runLimits) chunkSize() int64 {
+	const defaultChunk = 4096
+	if l.Rate <= 0 {
+		return defaultChunk
+	}
+	size := int64(l.Rate / 10)
+	if size < 1 {
+		return 1
+	}
+	if size > defaultChunk {
+		return defaultChunk
+	}
+	return size
+}
+
+// Общий для всех потоков ограничитель нагрузки
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration // время на одного кандидата при ограничении скорости
+	next     time.Time     // момент, начиная с которого можно брать следующую порцию
+	cpu      int
+}
+
+func newThrottle(l runLimits) *throttle {
+	t := &throttle{cpu: l.CPUPercent}
+	if l.Rate > 0 {
+		t.interval = time.Duration(float64(time.Second) / l.Rate)
+	}
+	return t
+}
+
+// Ожидание перед проверкой порции из n кандидатов (ограничение скорости)
+func (t *throttle) acquire(ctx context.Context, n int64) error {
+	if t.interval == 0 {
+		return ctx.Err()
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(n) * t.interval)
+	t.mu.Unlock()
+	return sleepContext(ctx, wait)
+}
+
+// Пауза после проверки порции, занявшей busy, для достижения целевой загрузки CPU
+func (t *throttle) rest(ctx context.Context, busy time.Duration) error {
+	if t.cpu <= 0 || t.cpu >= 100 {
+		return ctx.Err()
+	}
+	return sleepContext(ctx, busy*time.Duration(100-t.cpu)/time.Duration(t.cpu))
+}
+
+// Сон, прерываемый отменой контекста
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Учёт проверенных диапазонов. Порции завершаются в произвольном порядке,
+// а в контрольную точку попадает граница, до которой проверено всё без пропусков.
+type progress struct {
+	mu       sync.Mutex
+	frontier int64
+	done     map[int64]int64 // начало -> конец завершённых порций за границей
+	checked  int64
+}
+
+func newProgress(start int64) *progress {
+	return &progress{frontier: start, done: make(map[int64]int64)}
+}
+
+// Отметка диапазона [start, end) как проверенного
+func (p *progress) complete(start, end int64) {
+	if end <= start {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checked += end - start
+	p.done[start] = end
+	for {
+		next, ok := p.done[p.frontier]
+		if !ok {
+			break
+		}
+		delete(p.done, p.frontier)
+		p.frontier = next
+	}
+}
+
+func (p *progress) snapshot() (frontier, checked int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frontier, p.checked
+}
+
+// Контрольная точка для продолжения прерванного перебора
+type checkpoint struct {
+	Offset int64             `json:"offset"` // все кандидаты с меньшими номерами проверены
+	Total  int64             `json:"total"`  // количество кандидатов в источнике
+	Source string            `json:"source"` // описание источника кандидатов
+	Hashes string            `json:"hashes"` // отпечаток списка взламываемых хэшей
+	Found  map[string]string `json:"found"`  // хэш -> пароль
+}
+
+// Отпечаток списка хэшей: SHA-256 от отсортированных хэшей. Продолжать
+// с контрольной точки можно только для того же списка, иначе диапазон,
+// проверенный для старых хэшей, был бы пропущен для новых.
+func hashesFingerprint(hashes map[string]struct{}) string {
+	h := sha256.New()
+	for _, hash := range slices.Sorted(maps.Keys(hashes)) {
+		h.Write([]byte(hash))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, err
+	}
+	if cp.Found == nil {
+		cp.Found = make(map[string]string)
+	}
+	return cp, nil
+}
+
+// Запись контрольной точки через временный файл, чтобы не оставить её обрезанной
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Удаление контрольной точки после полного перебора
+func removeCheckpoint(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,62 +31,92 @@ func sha256Hash(password string) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-// Проверка пароля на соответствие хэшам
-func checkPassword(password string, hashes map[string]struct{}) bool {
+// Проверка пароля на соответствие хэшам, возвращает совпавший хэш
+func checkPassword(password string, hashes map[string]struct{}) (string, bool) {
 	md5HashResult := md5Hash(password)
-	sha256HashResult := sha256Hash(password)
-	_, exists := hashes[md5HashResult]
-	if exists {
-		return true
+	if _, exists := hashes[md5HashResult]; exists {
+		return md5HashResult, true
 	}
-	_, exists = hashes[sha256HashResult]
-	return exists
+	sha256HashResult := sha256Hash(password)
+	_, exists := hashes[sha256HashResult]
+	return sha256HashResult, exists
 }
 
-// Генерация всех пятибуквенных паролей
-func generatePasswords() []string {
-	var passwords []string
-	letters := "abcdefghijklmnopqrstuvwxyz"
-	for a := 0; a < len(letters); a++ {
-		for b := 0; b < len(letters); b++ {
-			for c := 0; c < len(letters); c++ {
-				for d := 0; d < len(letters); d++ {
-					for e := 0; e < len(letters); e++ {
-						passwords = append(passwords, string(letters[a])+string(letters[b])+string(letters[c])+string(letters[d])+string(letters[e]))
-					}
-				}
-			}
-		}
-	}
-	return passwords
+// Найденный пароль
+type hit struct {
+	threadID int
+	password string
+	hash     string
+	elapsed  time.Duration
+}
+
+// Итог запуска перебора
+type runResult struct {
+	found   map[string]string        // хэш -> пароль
+	foundAt map[string]time.Duration // хэш -> время находки от начала запуска
+	offset  int64                    // все кандидаты до этого номера проверены
+	checked int64                    // проверено кандидатов за этот запуск
+	elapsed time.Duration
+	reason  string
 }
 
-// Многопоточная версия алгоритма полного перебора
-func bruteForceMultiThread(hashes map[string]struct{}, numThreads int) {
+// Многопоточная версия алгоритма полного перебора.
+// Перебор начинается с кандидата start и останавливается по исчерпании
+// пространства ключей, по отмене ctx или при достижении ограничений limits.
+// Найденные пароли выводятся в out по мере нахождения.
+func bruteForceMultiThread(ctx context.Context, out io.Writer, hashes map[string]struct{}, numThreads int, src candidateSource, limits runLimits, start int64) runResult {
 	startTime := time.Now()
-	passwords := generatePasswords()
+	if limits.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.MaxRuntime)
+		defer cancel()
+	}
+
+	end := src.count()
+	if limits.MaxCandidates > 0 && start+limits.MaxCandidates < end {
+		end = start + limits.MaxCandidates
+	}
+	chunk := limits.chunkSize()
+	th := newThrottle(limits)
+	prog := newProgress(start)
+	var next atomic.Int64
+	next.Store(start)
 
 	var wg sync.WaitGroup
-	passwordsPerThread := len(passwords) / numThreads
-	ch := make(chan string)
+	ch := make(chan hit)
 
-	// Создаем пул потоков
+	// Создаем пул потоков, каждый поток забирает порции кандидатов из общего счётчика
 	for i := 0; i < numThreads; i++ {
 		wg.Add(1)
 		go func(threadID int) {
 			defer wg.Done()
 			threadStartTime := time.Now() // Засекаем время на выполнение этого потока
-			start := threadID * passwordsPerThread
-			end := (threadID + 1) * passwordsPerThread
-			if threadID == numThreads-1 {
-				end = len(passwords)
-			}
+			for {
+				from := next.Add(chunk) - chunk
+				if from >= end {
+					return
+				}
+				to := min(from+chunk, end)
+				if th.acquire(ctx, to-from) != nil {
+					return
+				}
 
-			// Перебор паролей в потоке
-			for _, password := range passwords[start:end] {
-				if checkPassword(password, hashes) {
-					elapsed := time.Since(threadStartTime) // Время на нахождение пароля
-					ch <- fmt.Sprintf("Поток %d - Пароль найден: %s (Время поиска: %d мс)", threadID+1, password, elapsed.Milliseconds())
+				// Перебор паролей порции
+				busyStart := time.Now()
+				idx := from
+				for ; idx < to; idx++ {
+					if idx&255 == 0 && ctx.Err() != nil {
+						break
+					}
+					password := src.at(idx)
+					if hash, ok := checkPassword(password, hashes); ok {
+						elapsed := time.Since(threadStartTime) // Время на нахождение пароля
+						ch <- hit{threadID: threadID, password: password, hash: hash, elapsed: elapsed}
+					}
+				}
+				prog.complete(from, idx)
+				if idx < to || th.rest(ctx, time.Since(busyStart)) != nil {
+					return
 				}
 			}
 		}(i)
@@ -91,31 +129,184 @@ func bruteForceMultiThread(hashes map[string]struct{}, numThreads int) {
 	}()
 
 	// Выводим результаты из канала
-	for result := range ch {
-		fmt.Println(result)
+	found := make(map[string]string)
+	foundAt := make(map[string]time.Duration)
+	for h := range ch {
+		fmt.Fprintf(out, "Поток %d - Пароль найден: %s (Время поиска: %d мс)\n", h.threadID+1, h.password, h.elapsed.Milliseconds())
+		found[h.hash] = h.password
+		foundAt[h.hash] = time.Since(startTime)
+	}
+
+	offset, checked := prog.snapshot()
+	res := runResult{found: found, foundAt: foundAt, offset: offset, checked: checked, elapsed: time.Since(startTime)}
+	switch {
+	case offset >= src.count():
+		res.reason = "все кандидаты перебраны"
+	case ctx.Err() == context.DeadlineExceeded:
+		res.reason = "достигнут лимит времени"
+	case ctx.Err() != nil:
+		res.reason = "перебор прерван"
+	default:
+		res.reason = "достигнут лимит кандидатов"
 	}
+	return res
+}
 
+// Вывод итогов перебора, в том числе частичных
+func printSummary(res runResult, found map[string]string, hashes map[string]struct{}, src candidateSource) {
 	// Выводим время на весь процесс
-	fmt.Printf("Общее время выполнения (многопоточность): %s\n", time.Since(startTime))
+	fmt.Printf("Общее время выполнения (многопоточность): %s\n", res.elapsed)
+	fmt.Printf("Причина остановки: %s\n", res.reason)
+	fmt.Printf("Проверено кандидатов: %d (проверено по порядку %d из %d)\n", res.checked, res.offset, src.count())
+	fmt.Printf("Найдено паролей: %d из %d\n", len(found), len(hashes))
+
+	keys := make([]string, 0, len(found))
+	for hash := range found {
+		keys = append(keys, hash)
+	}
+	sort.Strings(keys)
+	for _, hash := range keys {
+		fmt.Printf("  %s: %s\n", hash, found[hash])
+	}
 }
 
 func main() {
-	// Пример хэшей из задания
-	hashes := map[string]struct{}{
-		"1115dd800feaacefdf481f1f9070374a2a81e27880f187396db67958b207cbad": {},
-		"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b": {},
-		"74e1bb62f8dabb8125a58852b63bdf6eaef667cb56ac7f7cdba6d7305c50a22f": {},
-		"7a68f09bd992671bb3b19a5e70b7827e":                                 {},
+	threads := flag.Int("threads", 0, "количество потоков (0 — запросить с консоли)")
+	maxRuntime := flag.Duration("max-runtime", 0, "максимальное время перебора, например 10m (0 — без ограничения)")
+	maxCandidates := flag.Int64("max-candidates", 0, "максимальное количество проверяемых кандидатов (0 — без ограничения)")
+	rate := flag.Float64("rate", 0, "ограничение скорости, хэшей в секунду (0 — без ограничения)")
+	cpu := flag.Int("cpu", 100, "целевая загрузка процессора каждым потоком в процентах")
+	checkpointPath := flag.String("checkpoint", "lab2.checkpoint", "файл контрольной точки")
+	resume := flag.Bool("resume", false, "продолжить перебор с сохранённой контрольной точки")
+	potfile := flag.String("potfile", "lab2.pot", "файл, куда дописываются найденные пароли в виде хэш:пароль")
+	auditPath := flag.String("audit", "", "вывести анализ взломанных паролей из potfile или файла с итогами и выйти")
+	auditTop := flag.Int("audit-top", 10, "количество строк в рейтингах анализа")
+	hashesPath := flag.String("hashes", "", "файлы с хэшами через запятую, по одному хэшу в строке (по умолчанию — хэши из задания)")
+	mask := flag.String("mask", defaultMask, "маска перебора: ?l ?u ?d ?s ?a или символы как есть")
+	wordlistPath := flag.String("wordlist", "", "перебор по словарю (файл или каталог с файлами .txt) вместо маски")
+	usersPath := flag.String("gen-targeted", "", "сгенерировать словари по данным пользователей из файла и выйти")
+	genOut := flag.String("gen-out", "targeted", "каталог для словарей, созданных -gen-targeted")
+	fixtureCount := flag.Int("gen-fixtures", 0, "сгенерировать указанное количество тестовых хэшей по -mask или -wordlist и выйти")
+	fixtureAlgos := flag.String("fixture-algos", "md5,sha256", "алгоритмы для тестовых хэшей через запятую")
+	fixtureOut := flag.String("fixture-out", "fixtures", "каталог для hashes.txt и answers.txt")
+	seed := flag.Uint64("seed", 1, "начальное значение генератора тестовых данных")
+	flag.Parse()
+
+	if *auditPath != "" {
+		if err := runAudit(*auditPath, *auditTop); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	if *usersPath != "" {
+		if err := runTargetedGenerator(*usersPath, *genOut); err != nil {
+			fmt.Println(err)
+		}
+		return
 	}
 
-	var numThreads int
-	fmt.Println("Введите количество потоков:")
-	fmt.Scanln(&numThreads)
+	if *cpu < 1 || *cpu > 100 {
+		fmt.Println("Загрузка процессора должна быть от 1 до 100 процентов.")
+		return
+	}
+	limits := runLimits{
+		MaxRuntime:    *maxRuntime,
+		MaxCandidates: *maxCandidates,
+		Rate:          *rate,
+		CPUPercent:    *cpu,
+	}
+	var src candidateSource
+	if *wordlistPath != "" {
+		wl, err := loadWordlist(*wordlistPath)
+		if err != nil {
+			fmt.Println("Не удалось загрузить словарь:", err)
+			return
+		}
+		src = wl
+	} else {
+		ks, err := parseMask(*mask)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		src = ks
+	}
+
+	if *fixtureCount > 0 {
+		if err := runFixtureGenerator(src, *fixtureCount, strings.Split(*fixtureAlgos, ","), *seed, *fixtureOut); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	// Все списки проверяются за один проход по кандидатам
+	lists, err := loadHashLists(*hashesPath)
+	if err != nil {
+		fmt.Println("Не удалось загрузить хэши:", err)
+		return
+	}
+	hashes := mergeHashLists(lists)
+
+	numThreads := *threads
+	if numThreads == 0 {
+		fmt.Println("Введите количество потоков:")
+		fmt.Scanln(&numThreads)
+	}
 
 	if numThreads < 1 {
 		fmt.Println("Количество потоков должно быть не менее 1.")
 		return
 	}
 
-	bruteForceMultiThread(hashes, numThreads)
+	found := make(map[string]string)
+	var start int64
+	if *resume {
+		cp, err := loadCheckpoint(*checkpointPath)
+		if err != nil {
+			fmt.Println("Не удалось загрузить контрольную точку:", err)
+			return
+		}
+		if cp.Total != src.count() || cp.Source != src.String() {
+			fmt.Println("Контрольная точка относится к другому набору кандидатов.")
+			return
+		}
+		if cp.Hashes != hashesFingerprint(hashes) {
+			fmt.Println("Контрольная точка относится к другому списку хэшей.")
+			return
+		}
+		start = cp.Offset
+		found = cp.Found
+		fmt.Printf("Продолжение перебора с кандидата %d из %d\n", start, src.count())
+	}
+
+	// Ctrl+C останавливает перебор так же, как и достижение ограничения
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	res := bruteForceMultiThread(ctx, os.Stdout, hashes, numThreads, src, limits, start)
+	for hash, password := range res.found {
+		found[hash] = password
+	}
+	printSummary(res, found, hashes, src)
+	if len(lists) > 1 {
+		printListSummary(lists, found, res.foundAt)
+	}
+	if *potfile != "" && len(res.found) > 0 {
+		if err := appendPotfile(*potfile, res.found); err != nil {
+			fmt.Println("Не удалось записать potfile:", err)
+		}
+	}
+
+	if res.offset >= src.count() {
+		if err := removeCheckpoint(*checkpointPath); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	cp := checkpoint{Offset: res.offset, Total: src.count(), Source: src.String(), Hashes: hashesFingerprint(hashes), Found: found}
+	if err := saveCheckpoint(*checkpointPath, cp); err != nil {
+		fmt.Println("Не удалось сохранить контрольную точку:", err)
+		return
+	}
+	fmt.Printf("Контрольная точка сохранена в %s, для продолжения запустите программу с флагом -resume\n", *checkpointPath)
 }
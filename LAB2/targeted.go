@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Сведения о пользователе для генерации целевых кандидатов
+type userInfo struct {
+	Username  string
+	Name      string // имя и фамилия через пробел
+	BirthYear string
+	Company   string
+}
+
+// Чтение пользователей. Каждая строка: username[,name[,birth_year[,company]]],
+// поэтому подходит и простой список логинов вроде login_list.txt из LAB3.
+// Строки, начинающиеся с #, пропускаются.
+func readUsers(r io.Reader) ([]userInfo, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var users []userInfo
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]string, 4)
+		copy(fields, record)
+		if fields[0] == "" || fields[0] == "username" {
+			continue
+		}
+		users = append(users, userInfo{
+			Username:  fields[0],
+			Name:      fields[1],
+			BirthYear: fields[2],
+			Company:   fields[3],
+		})
+	}
+}
+
+// Разбиение на слова по пробелам и разделителям логинов (. _ - @)
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Замены символов на похожие цифры и знаки
+var leetReplacer = strings.NewReplacer("a", "@", "e", "3", "i", "1", "o", "0", "s", "$")
+
+func capitalize(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// Базовые слова пользователя: логин и его части, имя, фамилия,
+// их сочетания и название компании
+func userBaseWords(u userInfo) []string {
+	var bases []string
+	bases = append(bases, strings.ToLower(u.Username))
+	bases = append(bases, splitWords(u.Username)...)
+
+	name := splitWords(u.Name)
+	bases = append(bases, name...)
+	if len(name) >= 2 {
+		first, last := name[0], name[len(name)-1]
+		bases = append(bases,
+			first+last,
+			last+first,
+			string([]rune(first)[:1])+last,
+			first+string([]rune(last)[:1]),
+			first+"."+last,
+		)
+	}
+
+	company := splitWords(u.Company)
+	bases = append(bases, strings.Join(company, ""))
+	bases = append(bases, company...)
+	return bases
+}
+
+// Окончания, которые пользователи обычно добавляют к базовому слову
+func userSuffixes(u userInfo) []string {
+	suffixes := []string{"", "1", "12", "123", "1234", "!", "1!", "123!"}
+	if year := strings.TrimSpace(u.BirthYear); year != "" {
+		suffixes = append(suffixes, year, year+"!", "@"+year)
+		if len(year) == 4 {
+			suffixes = append(suffixes, year[2:], year[2:]+"!")
+		}
+	}
+	return suffixes
+}
+
+// Генерация изменённых кандидатов для одного пользователя без повторов
+func targetedCandidates(u userInfo) []string {
+	var candidates []string
+	seen := make(map[string]struct{})
+	add := func(c string) {
+		if _, dup := seen[c]; dup || c == "" {
+			return
+		}
+		seen[c] = struct{}{}
+		candidates = append(candidates, c)
+	}
+
+	suffixes := userSuffixes(u)
+	for _, base := range userBaseWords(u) {
+		if base == "" {
+			continue
+		}
+		forms := []string{base, capitalize(base), strings.ToUpper(base), leetReplacer.Replace(base)}
+		for _, form := range forms {
+			for _, suffix := range suffixes {
+				add(form + suffix)
+			}
+		}
+	}
+	return candidates
+}
+
+// Имя файла словаря для пользователя. Такое же правило используется
+// в LAB3/passwordCracker при поиске словаря для логина.
+func userListFileName(username string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-') {
+			return r
+		}
+		return '_'
+	}, username)
+	return safe + ".txt"
+}
+
+// Запись словаря в файл, по одному кандидату в строке
+func writeCandidates(path string, candidates []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		if _, err := fmt.Fprintln(file, c); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// Режим генерации: по файлу с пользователями в каталоге outDir создаётся
+// словарь <логин>.txt для каждого пользователя. Каталог можно передать
+// в -wordlist этой программы или в -userlists переборщика LAB3.
+func runTargetedGenerator(usersPath, outDir string) error {
+	file, err := os.Open(usersPath)
+	if err != nil {
+		return err
+	}
+	users, err := readUsers(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		candidates := targetedCandidates(u)
+		path := filepath.Join(outDir, userListFileName(u.Username))
+		if err := writeCandidates(path, candidates); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d кандидатов -> %s\n", u.Username, len(candidates), path)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Словарь кандидатов, загруженный из файла или каталога с файлами .txt
+type wordlist struct {
+	path  string
+	words []string
+}
+
+// Загрузка словаря. Для каталога читаются все файлы *.txt в алфавитном порядке,
+// повторяющиеся слова пропускаются.
+func loadWordlist(path string) (wordlist, error) {
+	wl := wordlist{path: path}
+	files := []string{path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return wl, err
+	}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.txt"))
+		if err != nil {
+			return wl, err
+		}
+		sort.Strings(files)
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return wl, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			word := strings.TrimRight(scanner.Text(), "\r")
+			if _, dup := seen[word]; dup || word == "" {
+				continue
+			}
+			seen[word] = struct{}{}
+			wl.words = append(wl.words, word)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return wl, err
+		}
+	}
+	return wl, nil
+}
+
+func (w wordlist) count() int64 {
+	return int64(len(w.words))
+}
+
+func (w wordlist) at(index int64) string {
+	return w.words[index]
+}
+
+func (w wordlist) String() string {
+	return "wordlist " + w.path
+}
@@ -1,82 +1,35 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"maps"
 	"net/http"
-	"time"
-)
-
-type User struct {
-	Username string
-	Password string
-	Token    string
-}
+	"os"
+	"slices"
+	"strings"
 
-var users = map[string]User{
-	"admin": User{
-		Username: "admin",
-		Password: "password",
-	},
-}
+	"auth/server"
+)
 
 func main() {
+	tokensPath := flag.String("tokens", "tokens.txt", "файл, в который записываются токены пользователей (доступен только владельцу)")
+	flag.Parse()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/login", loginHandler)
-	mux.HandleFunc("/check-token", checkTokenHandler)
-
-	http.ListenAndServe(":8080", mux)
-}
+	srv := server.New(server.DefaultUsers)
 
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
-	password := r.FormValue("password")
-	token := r.FormValue("token")
-
-	user, ok := users[username]
-	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
-
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+	// Токены записываются в файл, а не в консоль, чтобы не попадать в логи
+	var sb strings.Builder
+	for _, username := range slices.Sorted(maps.Keys(server.DefaultUsers)) {
+		fmt.Fprintf(&sb, "%s %s\n", username, srv.Token(username))
 	}
-
-	if token != user.Token {
-		w.WriteHeader(http.StatusUnauthorized)
+	if err := os.WriteFile(*tokensPath, []byte(sb.String()), 0o600); err != nil {
+		fmt.Println(err)
 		return
 	}
+	fmt.Println("Токены пользователей записаны в", *tokensPath)
 
-	w.WriteHeader(http.StatusOK)
-}
-
-func checkTokenHandler(w http.ResponseWriter, r *http.Request) {
-	token := r.FormValue("token")
-
-	_, ok := users[token]
-	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-}
-
-func generateToken(username string) string {
-	token := fmt.Sprintf("%s-%d", username, time.Now().Unix())
-	hash, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
-	if err != nil {
-		panic(err)
-	}
-
-	return string(hash)
-}
-
-func init() {
-	for username, user := range users {
-		user.Token = generateToken(username)
+	if err := http.ListenAndServe(":8080", srv); err != nil {
+		fmt.Println(err)
 	}
 }
@@ -0,0 +1,197 @@
+// Пакет server — обработчики системы аутентификации LAB3: вход по логину,
+// паролю и токену (/login) и проверка токена (/check-token). Вынесены из main,
+// чтобы систему можно было запустить в процессе переборщика и проверить,
+// что её защита останавливает атаки.
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type User struct {
+	Username string
+	Password string // bcrypt-хэш пароля
+	Token    string
+}
+
+// DefaultUsers — учётные записи по умолчанию, логин -> пароль
+var DefaultUsers = map[string]string{
+	"admin": "password",
+}
+
+// Пороги защиты /login от перебора
+const (
+	MaxFailures = 10               // неудачных входов подряд, после которых логин блокируется
+	LockoutTime = 15 * time.Minute // длительность блокировки логина
+	MaxRequests = 30               // попыток входа с одного адреса за RateWindow
+	RateWindow  = time.Minute
+)
+
+// Server — обработчик HTTP системы аутентификации
+type Server struct {
+	mux   *http.ServeMux
+	users map[string]User
+
+	mu       sync.Mutex
+	failures map[string]int        // логин -> неудачных входов подряд
+	locked   map[string]time.Time  // логин -> конец блокировки
+	requests map[string]*rateCount // адрес клиента -> попытки в текущем окне
+}
+
+// Попытки входа с одного адреса в окне RateWindow
+type rateCount struct {
+	start time.Time
+	count int
+}
+
+// New создаёт систему с учётными записями users (логин -> пароль).
+// Пароли хранятся в виде bcrypt-хэшей, токены генерируются при создании.
+func New(users map[string]string) *Server {
+	s := &Server{
+		mux:      http.NewServeMux(),
+		users:    make(map[string]User, len(users)),
+		failures: make(map[string]int),
+		locked:   make(map[string]time.Time),
+		requests: make(map[string]*rateCount),
+	}
+	for username, password := range users {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			panic(err)
+		}
+		s.users[username] = User{
+			Username: username,
+			Password: string(hash),
+			Token:    generateToken(username),
+		}
+	}
+	s.mux.HandleFunc("/login", s.loginHandler)
+	s.mux.HandleFunc("/check-token", s.checkTokenHandler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Token возвращает токен пользователя, пустую строку — если его нет
+func (s *Server) Token(username string) string {
+	return s.users[username].Token
+}
+
+// Вход по логину, паролю и токену. Попытки с одного адреса ограничены
+// MaxRequests за RateWindow (429), а после MaxFailures неудач подряд логин
+// блокируется на LockoutTime (423) и не принимает даже верный пароль.
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	if wait, ok := s.allow(clientAddr(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+	token := r.FormValue("token")
+
+	user, ok := s.users[username]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if s.isLocked(username) {
+		w.WriteHeader(http.StatusLocked)
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		s.fail(username)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if token != user.Token {
+		s.fail(username)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.failures, username)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// Адрес клиента без порта
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Учёт попытки входа с адреса addr. Если попыток в текущем окне больше
+// MaxRequests, возвращает false и время до начала следующего окна.
+func (s *Server) allow(addr string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	rc := s.requests[addr]
+	if rc == nil || now.Sub(rc.start) >= RateWindow {
+		rc = &rateCount{start: now}
+		s.requests[addr] = rc
+	}
+	rc.count++
+	if rc.count > MaxRequests {
+		return rc.start.Add(RateWindow).Sub(now), false
+	}
+	return 0, true
+}
+
+func (s *Server) isLocked(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.locked[username])
+}
+
+// Неудачный вход: после MaxFailures неудач подряд логин блокируется
+func (s *Server) fail(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[username]++
+	if s.failures[username] >= MaxFailures {
+		s.locked[username] = time.Now().Add(LockoutTime)
+		delete(s.failures, username)
+	}
+}
+
+func (s *Server) checkTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+
+	for _, user := range s.users {
+		if token != "" && token == user.Token {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func generateToken(username string) string {
+	token := fmt.Sprintf("%s-%d", username, time.Now().Unix())
+	hash, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(hash)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Способы записи паролей в журнал и отчёт
+const (
+	maskHash   = "hash"   // первые 16 символов HMAC-SHA-256 с ключом запуска
+	maskRedact = "redact" // первый и последний символы, остальное звёздочками
+	maskPlain  = "plain"  // как есть
+)
+
+// Случайный ключ HMAC на время запуска. Он нигде не сохраняется, поэтому
+// по журналу и отчёту пароли не восстановить перебором словаря, как
+// было бы с обычным хэшем.
+var maskKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// Пароль в виде для журнала и отчёта. В режиме hash одинаковые пароли
+// в пределах одного запуска записываются одинаково, что позволяет
+// сопоставить записи, но сверить их со словарём нельзя.
+func maskPassword(pass, mode string) string {
+	switch mode {
+	case maskPlain:
+		return pass
+	case maskRedact:
+		n := utf8.RuneCountInString(pass)
+		if n <= 2 {
+			return "***"
+		}
+		first, _ := utf8.DecodeRuneInString(pass)
+		last, _ := utf8.DecodeLastRuneInString(pass)
+		return string(first) + "***" + string(last)
+	default:
+		mac := hmac.New(sha256.New, maskKey)
+		mac.Write([]byte(pass))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+}
+
+func checkMaskMode(mode string) error {
+	switch mode {
+	case maskHash, maskRedact, maskPlain:
+		return nil
+	}
+	return fmt.Errorf("неизвестный способ записи паролей %q: hash, redact или plain", mode)
+}
+
+// Запись журнала об одной попытке
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Password  string    `json:"password"`
+	Verdict   string    `json:"verdict"`
+	Status    int       `json:"status"`
+	Length    int       `json:"length"`
+	LatencyMS float64   `json:"latency_ms"`
+	Tries     int       `json:"tries"`
+	Defense   string    `json:"defense,omitempty"`
+	Proxy     string    `json:"proxy,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Журнал попыток в формате JSON Lines: по объекту на строку.
+// Файл дополняется, чтобы при продолжении атаки журнал оставался общим.
+type auditLog struct {
+	f    *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	mask string
+}
+
+func openAuditLog(path, mask string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &auditLog{f: f, w: w, enc: json.NewEncoder(w), mask: mask}, nil
+}
+
+func (a *auditLog) write(r result) error {
+	rec := auditRecord{
+		Time:      time.Now(),
+		User:      r.login,
+		Password:  maskPassword(r.pass, a.mask),
+		Verdict:   r.verdict.String(),
+		Status:    r.status,
+		Length:    r.length,
+		LatencyMS: float64(r.elapsed) / float64(time.Millisecond),
+		Tries:     r.tries,
+		Defense:   r.defense,
+		Proxy:     r.proxy,
+	}
+	if r.err != nil {
+		rec.Error = r.err.Error()
+	}
+	return a.enc.Encode(rec)
+}
+
+// Запись накопленных строк на диск, чтобы журнал не терялся при аварийной остановке
+func (a *auditLog) flush() error {
+	return a.w.Flush()
+}
+
+func (a *auditLog) Close() error {
+	if err := a.w.Flush(); err != nil {
+		a.f.Close()
+		return err
+	}
+	return a.f.Close()
+}
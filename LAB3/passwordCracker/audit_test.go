@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMaskPassword(t *testing.T) {
+	for _, c := range []struct {
+		pass, mode, want string
+	}{
+		{"password", maskPlain, "password"},
+		{"password", maskRedact, "p***d"},
+		{"пароль", maskRedact, "п***ь"},
+		{"ab", maskRedact, "***"},
+	} {
+		if got := maskPassword(c.pass, c.mode); got != c.want {
+			t.Errorf("%q, %s: %q, ожидалось %q", c.pass, c.mode, got, c.want)
+		}
+	}
+
+	// В режиме hash запись стабильна в пределах запуска, различает пароли
+	// и не совпадает с хэшем без ключа, который сверяется со словарём
+	a, b := maskPassword("password", maskHash), maskPassword("password", maskHash)
+	if a != b {
+		t.Errorf("один пароль записан по-разному: %q и %q", a, b)
+	}
+	if a == maskPassword("password1", maskHash) {
+		t.Error("разные пароли записаны одинаково")
+	}
+	sum := sha256.Sum256([]byte("password"))
+	if strings.Contains(a, "password") || strings.Contains(a, hex.EncodeToString(sum[:8])) {
+		t.Errorf("запись %q раскрывает пароль", a)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"cracker/mockdvwa"
+)
+
+// Функция для чтения учётных записей: логин:пароль в каждой строке
+func readUsers(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		login, pass, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s: ожидалось логин:пароль, получено %q", path, line)
+		}
+		users[login] = pass
+	}
+	return users, scanner.Err()
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "адрес, на котором слушает сервер")
+	usersPath := flag.String("users", "", "файл с учётными записями логин:пароль (по умолчанию пользователи DVWA)")
+	security := flag.String("security", "impossible", "уровень защиты, выставляемый при входе: low, medium, high, impossible")
+	timeScale := flag.Float64("time-scale", 1, "множитель задержек DVWA (0 — без задержек)")
+	lockoutAttempts := flag.Int("lockout-attempts", 3, "неудачных попыток до блокировки на уровне impossible")
+	lockoutTime := flag.Duration("lockout-time", 15*time.Minute, "длительность блокировки на уровне impossible")
+	seed := flag.Uint64("seed", 1, "seed для случайных задержек")
+	flag.Parse()
+
+	cfg := mockdvwa.Config{
+		Security:        *security,
+		TimeScale:       *timeScale,
+		LockoutAttempts: *lockoutAttempts,
+		LockoutTime:     *lockoutTime,
+		Seed:            *seed,
+	}
+	if *usersPath != "" {
+		users, err := readUsers(*usersPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		cfg.Users = users
+	}
+
+	fmt.Printf("Имитация DVWA: http://%s%s (вход: http://%s%s)\n", *addr, mockdvwa.BrutePath, *addr, mockdvwa.LoginPath)
+	if err := http.ListenAndServe(*addr, mockdvwa.New(cfg)); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Виды средств защиты, которые распознаются по ответам
+const (
+	defenseLockout   = "блокировка учётной записи"
+	defenseCaptcha   = "CAPTCHA"
+	defenseRateLimit = "ограничение частоты запросов"
+	defenseSlowdown  = "рост времени ответа"
+	defenseLength    = "изменение длины ответа"
+)
+
+// Средство защиты, сработавшее на ответ, по правилам классификации
+func (c *classifier) defense(r *response) string {
+	switch {
+	case c.captcha != nil && c.captcha.match(r):
+		return defenseCaptcha
+	case r.status == http.StatusTooManyRequests || r.status == http.StatusServiceUnavailable:
+		return defenseRateLimit
+	case c.lockout != nil && c.lockout.match(r):
+		return defenseLockout
+	}
+	return ""
+}
+
+// Настройки обнаружения защиты
+type defenseConfig struct {
+	Pause      time.Duration // пауза для логина после срабатывания защиты, удваивается при повторах
+	TimeFactor float64       // ответ во столько раз дольше среднего считается признаком защиты, 0 — не проверять
+	LengthDiff int           // отклонение длины ответа от обычной больше этого — признак защиты, <0 — не проверять
+}
+
+// Скользящее среднее с отклонением по ответам на неудачные попытки
+type runningStat struct {
+	n         int
+	mean, dev float64
+}
+
+func (s *runningStat) add(x float64) {
+	s.n++
+	if s.n == 1 {
+		s.mean = x
+		return
+	}
+	const alpha = 1.0 / 16
+	s.dev += alpha * (math.Abs(x-s.mean) - s.dev)
+	s.mean += alpha * (x - s.mean)
+}
+
+// Наблюдение за средствами защиты: по ответам распознаются блокировки,
+// CAPTCHA, ограничение частоты и резкие изменения времени и длины ответов
+// на неудачные попытки. Логин, на котором сработала защита, ставится на паузу.
+type defenseMonitor struct {
+	cfg defenseConfig
+	mu  sync.Mutex
+
+	paused  map[string]time.Time // логин -> конец паузы
+	strikes map[string]int       // логин -> срабатываний подряд
+	elapsed runningStat
+	length  runningStat
+
+	events map[string]*defenseEvents
+}
+
+// Сводка по одному виду защиты
+type defenseEvents struct {
+	count int
+	first time.Time
+	users map[string]struct{}
+	proof result // попытка, на которой защита замечена впервые
+}
+
+func newDefenseMonitor(cfg defenseConfig) *defenseMonitor {
+	return &defenseMonitor{
+		cfg:     cfg,
+		paused:  make(map[string]time.Time),
+		strikes: make(map[string]int),
+		events:  make(map[string]*defenseEvents),
+	}
+}
+
+// Ожидание конца паузы для логина
+func (m *defenseMonitor) waitUser(ctx context.Context, login string) error {
+	m.mu.Lock()
+	until := m.paused[login]
+	m.mu.Unlock()
+	return sleepContext(ctx, time.Until(until))
+}
+
+// Проверка результата попытки. Возвращает вид сработавшей защиты или пустую строку.
+func (m *defenseMonitor) inspect(r result) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kind := r.defense
+	if kind == "" && r.verdict == verdictFailure {
+		kind = m.anomaly(r)
+	}
+	if kind == "" {
+		if r.verdict != verdictError {
+			m.strikes[r.login] = 0
+		}
+		return ""
+	}
+
+	ev := m.events[kind]
+	if ev == nil {
+		ev = &defenseEvents{first: time.Now(), users: make(map[string]struct{}), proof: r}
+		m.events[kind] = ev
+	}
+	ev.count++
+	ev.users[r.login] = struct{}{}
+
+	// Пока логин на паузе, ответы на уже начатые попытки паузу не продлевают
+	if time.Now().Before(m.paused[r.login]) {
+		return kind
+	}
+	pause := m.cfg.Pause << min(m.strikes[r.login], 6)
+	m.strikes[r.login]++
+	m.paused[r.login] = time.Now().Add(pause)
+	fmt.Printf("ЗАЩИТА: %s на логине %v (status code = %v), пауза %s\n", kind, r.login, r.status, pause)
+	return kind
+}
+
+// Сравнение ответа на неудачную попытку с обычными: после накопления
+// статистики резкий рост времени или изменение длины считаются признаком защиты
+func (m *defenseMonitor) anomaly(r result) string {
+	const warmup = 20
+	elapsed, length := float64(r.elapsed), float64(r.length)
+	kind := ""
+	switch {
+	case m.elapsed.n < warmup:
+	case m.cfg.TimeFactor > 0 && elapsed > m.cfg.TimeFactor*(m.elapsed.mean+m.elapsed.dev) &&
+		r.elapsed > 100*time.Millisecond:
+		kind = defenseSlowdown
+	case m.cfg.LengthDiff >= 0 && math.Abs(length-m.length.mean) > float64(m.cfg.LengthDiff)+4*m.length.dev:
+		kind = defenseLength
+	}
+	// Ответы с признаками защиты в обычную статистику не попадают
+	if kind == "" {
+		m.elapsed.add(elapsed)
+		m.length.add(length)
+	}
+	return kind
+}
+
+// Попытка с учётом защиты: вид защиты, замеченной по ответу, записывается
+// в результат, чтобы попасть в журнал и отчёт. Повторяются только попытки,
+// отклонённые ограничением частоты: приложение их не проверяло, а паузу
+// перед повтором выдерживает ограничитель нагрузки. Попытки с блокировкой
+// или CAPTCHA не повторяются: лишние неудачные входы только приблизили бы
+// блокировку, такие пары попадают в непроверенные.
+func (m *defenseMonitor) guard(attempt func(context.Context, job) result) func(context.Context, job) result {
+	const maxRetries = 3
+	return func(ctx context.Context, j job) result {
+		for i := 0; ; i++ {
+			r := attempt(ctx, j)
+			r.defense = m.inspect(r)
+			if r.defense != defenseRateLimit || i == maxRetries {
+				return r
+			}
+		}
+	}
+}
+
+// Передача заданий из in в out с задержкой заданий логинов, стоящих
+// на паузе: ждёт производитель, а потоки продолжают уже выданные попытки
+func (m *defenseMonitor) hold(ctx context.Context, in <-chan job, out chan<- job) {
+	for j := range in {
+		if m.waitUser(ctx, j.login) != nil || sendJob(ctx, out, j) != nil {
+			// Производитель должен увидеть отмену и закрыть in
+			for range in {
+			}
+			break
+		}
+	}
+}
+
+// Обнаруженное средство защиты для отчёта
+type defenseSummary struct {
+	Kind  string    `json:"kind"`
+	Count int       `json:"count"`
+	Users int       `json:"users"`
+	First time.Time `json:"first"`
+	Proof string    `json:"proof"`
+}
+
+// Обнаруженные средства защиты в порядке первого срабатывания.
+// Пароль в примере ответа записывается способом mask, как в журнале.
+func (m *defenseMonitor) summary(mask string) []defenseSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]defenseSummary, 0, len(m.events))
+	for kind, ev := range m.events {
+		p := ev.proof
+		proof := fmt.Sprintf("%s / %s: status code = %v, длина %d, время %s",
+			p.login, maskPassword(p.pass, mask), p.status, p.length, p.elapsed.Round(time.Microsecond))
+		list = append(list, defenseSummary{Kind: kind, Count: ev.count, Users: len(ev.users), First: ev.first, Proof: proof})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].First.Before(list[j].First) })
+	return list
+}
+
+// Отчёт об обнаруженных средствах защиты
+func (m *defenseMonitor) report() {
+	list := m.summary(maskPlain)
+	if len(list) == 0 {
+		fmt.Println("Средства защиты не обнаружены")
+		return
+	}
+	fmt.Println("Обнаруженные средства защиты:")
+	for _, d := range list {
+		fmt.Printf("  %s: срабатываний %d, логинов %d, впервые в %s\n",
+			d.Kind, d.Count, d.Users, d.First.Format("15:04:05"))
+		fmt.Printf("    %s\n", d.Proof)
+	}
+}
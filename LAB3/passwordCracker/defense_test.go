@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Пароль в примере ответа для отчёта записывается выбранным способом
+func TestDefenseSummaryMasksPassword(t *testing.T) {
+	m := newDefenseMonitor(defenseConfig{Pause: time.Millisecond})
+	m.inspect(result{
+		job:     job{login: "admin", pass: "s3cret-pass"},
+		verdict: verdictLockout,
+		status:  429,
+		length:  10,
+		defense: defenseRateLimit,
+	})
+
+	for _, mask := range []string{maskHash, maskRedact, maskPlain} {
+		list := m.summary(mask)
+		if len(list) != 1 {
+			t.Fatalf("%s: средств защиты %d, ожидалось 1", mask, len(list))
+		}
+		proof := list[0].Proof
+		if want := maskPassword("s3cret-pass", mask); !strings.Contains(proof, "admin / "+want+":") {
+			t.Errorf("%s: пример %q, ожидался пароль %q", mask, proof, want)
+		}
+		if mask != maskPlain && strings.Contains(proof, "s3cret-pass") {
+			t.Errorf("%s: пароль в примере не скрыт: %q", mask, proof)
+		}
+		if !strings.Contains(proof, "status code = 429") {
+			t.Errorf("%s: в примере нет кода ответа: %q", mask, proof)
+		}
+	}
+}
+
+// Защита, замеченная по ответу, записывается в результат; повторяются
+// только попытки, отклонённые ограничением частоты
+func TestDefenseGuard(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		responses   []result // ответы на попытки по порядку, последний повторяется
+		wantCalls   int
+		wantDefense string
+		wantVerdict verdict
+	}{
+		{"блокировка не повторяется",
+			[]result{{verdict: verdictLockout, defense: defenseLockout}}, 1, defenseLockout, verdictLockout},
+		{"CAPTCHA не повторяется",
+			[]result{{verdict: verdictLockout, defense: defenseCaptcha}}, 1, defenseCaptcha, verdictLockout},
+		{"ограничение частоты, затем ответ",
+			[]result{{verdict: verdictLockout, defense: defenseRateLimit}, {verdict: verdictFailure}}, 2, "", verdictFailure},
+		{"ограничение частоты до предела повторов",
+			[]result{{verdict: verdictLockout, defense: defenseRateLimit}}, 4, defenseRateLimit, verdictLockout},
+		{"обычная неудача",
+			[]result{{verdict: verdictFailure}}, 1, "", verdictFailure},
+	} {
+		// Пауза длиннее проверки: попытки не должны её выдерживать
+		m := newDefenseMonitor(defenseConfig{Pause: time.Hour, LengthDiff: -1})
+		calls := 0
+		attempt := m.guard(func(ctx context.Context, j job) result {
+			r := c.responses[min(calls, len(c.responses)-1)]
+			r.job = j
+			calls++
+			return r
+		})
+		done := make(chan result, 1)
+		go func() { done <- attempt(context.Background(), job{login: "admin", pass: "x"}) }()
+		select {
+		case r := <-done:
+			if calls != c.wantCalls || r.defense != c.wantDefense || r.verdict != c.wantVerdict {
+				t.Errorf("%s: попыток %d, защита %q, %v; ожидалось %d, %q, %v",
+					c.name, calls, r.defense, r.verdict, c.wantCalls, c.wantDefense, c.wantVerdict)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: попытка ждёт паузу логина", c.name)
+		}
+	}
+}
+
+// Изменение длины ответа после накопления статистики попадает в результат
+func TestDefenseGuardRecordsAnomaly(t *testing.T) {
+	m := newDefenseMonitor(defenseConfig{Pause: time.Millisecond, LengthDiff: 16})
+	length := 100
+	attempt := m.guard(func(ctx context.Context, j job) result {
+		return result{job: j, verdict: verdictFailure, length: length, elapsed: time.Millisecond}
+	})
+	for i := 0; i < 25; i++ {
+		if r := attempt(context.Background(), job{login: "admin", pass: "x"}); r.defense != "" {
+			t.Fatalf("попытка %d: защита %q на обычном ответе", i+1, r.defense)
+		}
+	}
+	length = 400
+	if r := attempt(context.Background(), job{login: "admin", pass: "y"}); r.defense != defenseLength || r.verdict != verdictFailure {
+		t.Errorf("защита %q, %v; ожидалось %q, %v", r.defense, r.verdict, defenseLength, verdictFailure)
+	}
+}
+
+// Задание логина на паузе придерживается до её конца, а задания,
+// полученные раньше, проходят сразу
+func TestDefenseHold(t *testing.T) {
+	const pause = 100 * time.Millisecond
+	m := newDefenseMonitor(defenseConfig{Pause: pause})
+	m.inspect(result{job: job{login: "admin"}, verdict: verdictLockout, defense: defenseLockout})
+
+	in := make(chan job)
+	out := make(chan job)
+	go func() {
+		defer close(in)
+		for _, login := range []string{"gordonb", "admin", "pablo"} {
+			in <- job{login: login}
+		}
+	}()
+	go func() {
+		defer close(out)
+		m.hold(context.Background(), in, out)
+	}()
+	start := time.Now()
+	for j := range out {
+		elapsed := time.Since(start)
+		if j.login == "admin" && elapsed < pause-10*time.Millisecond {
+			t.Errorf("задание %s выдано через %s, до конца паузы", j.login, elapsed)
+		}
+		if j.login == "gordonb" && elapsed > pause/2 {
+			t.Errorf("задание %s задержано на %s", j.login, elapsed)
+		}
+	}
+
+	// После отмены вход дочитывается, чтобы производитель не завис
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	in = make(chan job)
+	go func() {
+		defer close(in)
+		for i := 0; i < 3; i++ {
+			in <- job{login: "admin"}
+		}
+	}()
+	m.hold(ctx, in, make(chan job))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Задание для пула: одна пара логин/пароль
+type job struct {
+	login string
+	pass  string
+}
+
+// Результат попытки входа
+type result struct {
+	job
+	verdict  verdict
+	status   int           // код ответа, 0 — ответ не получен
+	length   int           // длина тела ответа
+	err      error         // ошибка запроса или причина, по которой ответ не распознан
+	elapsed  time.Duration // время от отправки попытки до первого байта ответа
+	defense  string        // сработавшее средство защиты, если есть
+	bodyHash string        // хэш тела ответа без изменчивых частей, см. bodySignature
+	tries    int           // сколько раз выполнялась попытка с учётом повторов
+	proxy    string        // прокси, через который отправлена попытка
+}
+
+// Пул из workers горутин, выполняющих попытки из jobs. Результаты всех
+// начатых попыток отправляются в возвращаемый канал, который закрывается
+// после завершения всех горутин. После отмены ctx новые задания не берутся.
+func runPool(ctx context.Context, workers int, jobs <-chan job, attempt func(context.Context, job) result) <-chan result {
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j, ok := <-jobs:
+					if !ok {
+						return
+					}
+					results <- attempt(ctx, j)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// Отправка задания с учётом отмены
+func sendJob(ctx context.Context, jobs chan<- job, j job) error {
+	select {
+	case jobs <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Попытка входа по протоколу auth с классификацией ответа по правилам cls
+func loginAttempt(auth authenticator, cls *classifier) func(context.Context, job) result {
+	return func(ctx context.Context, j job) result {
+		r := result{job: j, verdict: verdictError}
+		ctx, elapsed := timedContext(ctx)
+		ctx, proxy := proxyNoteContext(ctx)
+		resp, err := auth.attempt(ctx, j.login, j.pass)
+		r.proxy = proxy()
+		if err != nil {
+			r.err = err
+			return r
+		}
+		defer resp.Body.Close()
+		r.status = resp.StatusCode
+		r.elapsed = elapsed()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		r.length = len(body)
+		r.bodyHash = bodySignature(body, j.login, j.pass)
+		rr := newResponse(resp, body)
+		r.verdict, r.err = cls.classify(rr)
+		r.defense = cls.defense(rr)
+		return r
+	}
+}
+
+// Контекст, замеряющий время ответа сервера: от отправки последнего запроса
+// до получения первого байта ответа. Ожидание в ограничителе и получение
+// токена в замер не входят.
+func timedContext(ctx context.Context) (context.Context, func() time.Duration) {
+	var mu sync.Mutex
+	var wrote, first time.Time
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			wrote, first = time.Now(), time.Time{}
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			first = time.Now()
+			mu.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), func() time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		if first.Before(wrote) {
+			return 0
+		}
+		return first.Sub(wrote)
+	}
+}
+
+// Длина ответа на заведомо неверную попытку со случайными логином и паролем
+func measureBaseline(ctx context.Context, auth authenticator) (int, error) {
+	random := make([]byte, 8)
+	rand.Read(random)
+	resp, err := auth.attempt(ctx, "baseline-"+hex.EncodeToString(random[:4]), hex.EncodeToString(random[4:]))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return len(body), err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Задания 0..n-1
+func numberedJobs(ctx context.Context, n int) <-chan job {
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			if sendJob(ctx, jobs, job{login: fmt.Sprint(i), pass: "x"}) != nil {
+				return
+			}
+		}
+	}()
+	return jobs
+}
+
+// Пул выполняет каждое задание ровно один раз, не больше workers
+// одновременно, и учитывает ошибки
+func TestRunPool(t *testing.T) {
+	const n = 200
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("потоков %d", workers), func(t *testing.T) {
+			var running, peak atomic.Int32
+			attempt := func(ctx context.Context, j job) result {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if cur <= old || peak.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Microsecond)
+				if j.login[len(j.login)-1] == '7' {
+					return result{job: j, verdict: verdictError, err: errors.New("сбой")}
+				}
+				return result{job: j, verdict: verdictFailure}
+			}
+
+			ctx := context.Background()
+			seen := make(map[string]int)
+			errs := 0
+			for r := range runPool(ctx, workers, numberedJobs(ctx, n), attempt) {
+				seen[r.login]++
+				if r.verdict == verdictError {
+					errs++
+				}
+			}
+			if len(seen) != n {
+				t.Errorf("выполнено %d разных заданий из %d", len(seen), n)
+			}
+			for login, count := range seen {
+				if count != 1 {
+					t.Errorf("задание %s выполнено %d раз", login, count)
+				}
+			}
+			if errs != n/10 {
+				t.Errorf("ошибок %d, ожидалось %d", errs, n/10)
+			}
+			if p := peak.Load(); p > int32(workers) {
+				t.Errorf("одновременно выполнялось %d попыток при %d потоках", p, workers)
+			}
+		})
+	}
+}
+
+// После отмены пул не берёт новых заданий и закрывает канал результатов
+func TestRunPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var started atomic.Int32
+	attempt := func(ctx context.Context, j job) result {
+		if started.Add(1) == 10 {
+			cancel()
+		}
+		return result{job: j, verdict: verdictFailure}
+	}
+
+	done := make(chan int)
+	go func() {
+		count := 0
+		for range runPool(ctx, 4, numberedJobs(ctx, 10000), attempt) {
+			count++
+		}
+		done <- count
+	}()
+	select {
+	case count := <-done:
+		if count >= 10000 {
+			t.Errorf("после отмены выполнены все %d заданий", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("канал результатов не закрыт после отмены")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Длинные шестнадцатеричные строки (токены, идентификаторы сессий)
+// меняются от ответа к ответу и при сравнении ответов не учитываются
+var volatileRegexp = regexp.MustCompile(`[0-9a-fA-F]{16,}`)
+
+// Хэш тела ответа без логина, пароля и меняющихся токенов: у одинаковых
+// по смыслу ответов для разных логинов хэш совпадает. Логин и пароль
+// заменяются только целыми словами, чтобы логин user не менял user_token в форме.
+func bodySignature(body []byte, login, pass string) string {
+	norm := volatileRegexp.ReplaceAll(body, []byte("#"))
+	for _, s := range []string{pass, login} {
+		norm = replaceWord(norm, s, "§")
+	}
+	sum := sha256.Sum256(norm)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Замена вхождений word, не окружённых буквами, цифрами и _.
+// Вызывается на каждую попытку, поэтому обходится без регулярных выражений.
+func replaceWord(b []byte, word, repl string) []byte {
+	if word == "" {
+		return b
+	}
+	w := []byte(word)
+	var out []byte
+	last := 0
+	for i := 0; ; {
+		j := bytes.Index(b[i:], w)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(w)
+		before, _ := utf8.DecodeLastRune(b[:start])
+		after, _ := utf8.DecodeRune(b[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(b) || !isWordRune(after)) {
+			out = append(append(out, b[last:start]...), repl...)
+			last, i = end, end
+		} else {
+			i = start + 1
+		}
+	}
+	if out == nil {
+		return b
+	}
+	return append(out, b[last:]...)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Ответы для одного логина
+type enumUser struct {
+	login   string
+	status  int
+	length  int
+	hash    string
+	elapsed []time.Duration
+}
+
+func (u *enumUser) median() time.Duration {
+	return medianDuration(u.elapsed)
+}
+
+func medianDuration(d []time.Duration) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	s := slices.Clone(d)
+	slices.Sort(s)
+	return s[len(s)/2]
+}
+
+// Группа логинов с одинаковыми ответами
+type enumCluster struct {
+	status int
+	hash   string
+	users  []*enumUser
+}
+
+func (c *enumCluster) key() string { return fmt.Sprintf("%d %s", c.status, c.hash) }
+
+// Перебор логинов с заведомо неверным паролем. Ответы группируются по коду,
+// телу без меняющихся частей и времени; логины, ответ на которые отличается
+// от ответа для большинства или заметно дольше, считаются существующими.
+// attempt — попытка входа, как при переборе паролей.
+func runEnumeration(ctx context.Context, workers int, attempt func(context.Context, job) result,
+	users []string, pass string, samples int) []string {
+	if pass == "" {
+		random := make([]byte, 8)
+		rand.Read(random)
+		pass = "enum-" + hex.EncodeToString(random)
+	}
+	fmt.Printf("Проверка %d логинов с паролем %q, попыток на логин: %d\n", len(users), pass, samples)
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < samples; i++ {
+			for _, login := range users {
+				if sendJob(ctx, jobs, job{login: login, pass: pass}) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	byLogin := make(map[string]*enumUser, len(users))
+	for _, login := range users {
+		byLogin[login] = &enumUser{login: login}
+	}
+	for r := range runPool(ctx, workers, jobs, attempt) {
+		u := byLogin[r.login]
+		if r.status == 0 {
+			fmt.Printf("%v: %v\n", r.login, r.err)
+			continue
+		}
+		// Для группировки берётся первый ответ, время учитывается по всем
+		if len(u.elapsed) == 0 {
+			u.status, u.length, u.hash = r.status, r.length, r.bodyHash
+		}
+		u.elapsed = append(u.elapsed, r.elapsed)
+	}
+
+	clusters := make(map[string]*enumCluster)
+	var answered []*enumUser
+	var medians []time.Duration
+	for _, login := range users {
+		u := byLogin[login]
+		if len(u.elapsed) == 0 {
+			continue
+		}
+		answered = append(answered, u)
+		medians = append(medians, u.median())
+		c := &enumCluster{status: u.status, hash: u.hash}
+		if prev, ok := clusters[c.key()]; ok {
+			c = prev
+		} else {
+			clusters[c.key()] = c
+		}
+		c.users = append(c.users, u)
+	}
+	if len(answered) == 0 {
+		fmt.Println("Ни на одну попытку не получен ответ")
+		return nil
+	}
+
+	sorted := make([]*enumCluster, 0, len(clusters))
+	for _, c := range clusters {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].users) > len(sorted[j].users) })
+
+	fmt.Println("Группы ответов:")
+	for i, c := range sorted {
+		var elapsed []time.Duration
+		for _, u := range c.users {
+			elapsed = append(elapsed, u.elapsed...)
+		}
+		fmt.Printf("  %d. логинов %d, status code = %v, длина %d, тело %s, время: медиана %s\n",
+			i+1, len(c.users), c.status, c.users[0].length, c.hash, medianDuration(elapsed).Round(time.Microsecond))
+	}
+
+	// Большая группа — ответ для несуществующих логинов. Медленным считается
+	// логин, у которого время ответа больше медианы по всем логинам хотя бы
+	// вдвое и на пять медианных отклонений, но не меньше чем на 5 мс:
+	// меньшие различия теряются в колебаниях сети.
+	median := medianDuration(medians)
+	var deviations []time.Duration
+	for _, m := range medians {
+		deviations = append(deviations, (m - median).Abs())
+	}
+	threshold := median + max(5*medianDuration(deviations), median, 5*time.Millisecond)
+
+	var valid []string
+	fmt.Println("Вероятно существующие логины:")
+	for _, u := range answered {
+		var reasons []string
+		if len(sorted) > 1 && (u.status != sorted[0].status || u.hash != sorted[0].hash) {
+			reasons = append(reasons, fmt.Sprintf("ответ отличается от большинства (status code = %v, длина %d)", u.status, u.length))
+		}
+		if m := u.median(); m > threshold {
+			reasons = append(reasons, fmt.Sprintf("время ответа %s при медиане %s", m.Round(time.Microsecond), median.Round(time.Microsecond)))
+		}
+		if len(reasons) > 0 {
+			valid = append(valid, u.login)
+			fmt.Printf("  %s: %s\n", u.login, strings.Join(reasons, "; "))
+		}
+	}
+	if len(valid) == 0 {
+		fmt.Println("  не найдены: ответы для всех логинов одинаковы")
+	}
+	if len(sorted) > 1 && 2*len(sorted[0].users) <= len(answered) {
+		fmt.Println("Внимание: ни одна группа не составляет большинство, разделение на существующие и несуществующие логины ненадёжно")
+	}
+	return valid
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestReplaceWord(t *testing.T) {
+	for _, c := range []struct {
+		body, word, want string
+	}{
+		{"Welcome, user!", "user", "Welcome, §!"},
+		{"user", "user", "§"},
+		{`<input name="user_token" value="x">`, "user", `<input name="user_token" value="x">`},
+		{"superuser user2 user", "user", "superuser user2 §"},
+		{"user user", "user", "§ §"},
+		{"Привет, админ.", "админ", "Привет, §."},
+		{"администратор", "админ", "администратор"},
+		{"a.b a.b", "a.b", "§ §"},
+		{"no match", "", "no match"},
+	} {
+		if got := string(replaceWord([]byte(c.body), c.word, "§")); got != c.want {
+			t.Errorf("replaceWord(%q, %q) = %q, ожидалось %q", c.body, c.word, got, c.want)
+		}
+	}
+}
+
+// У ответов, отличающихся только логином и токенами, сигнатуры совпадают
+func TestBodySignature(t *testing.T) {
+	a := bodySignature([]byte(`Hello, admin <input name="user_token" value="0123456789abcdef0123">`), "admin", "x")
+	b := bodySignature([]byte(`Hello, pablo <input name="user_token" value="fedcba98765432100000">`), "pablo", "y")
+	if a != b {
+		t.Errorf("сигнатуры различаются: %s и %s", a, b)
+	}
+	c := bodySignature([]byte(`Hello, pablo <input name="user_token" value="fedcba98765432100000"> locked`), "pablo", "y")
+	if a == c {
+		t.Error("сигнатуры разных ответов совпали")
+	}
+}
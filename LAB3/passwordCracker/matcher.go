@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Классификация попытки входа
+type verdict int
+
+const (
+	verdictFailure verdict = iota // неверные логин или пароль
+	verdictSuccess                // вход выполнен
+	verdictLockout                // учётная запись заблокирована или сработала защита
+	verdictError                  // ответ не получен или непонятен
+	verdictSkipped                // попытка отменена: пароль логина уже найден или атака остановлена
+)
+
+func (v verdict) String() string {
+	switch v {
+	case verdictSuccess:
+		return "success"
+	case verdictLockout:
+		return "lockout"
+	case verdictError:
+		return "error"
+	case verdictSkipped:
+		return "skipped"
+	default:
+		return "failure"
+	}
+}
+
+// Ответ на попытку входа в виде, удобном для проверки условий
+type response struct {
+	status   int
+	header   http.Header
+	body     []byte
+	baseline int // длина ответа на заведомо неверную попытку, -1 — не измерялась
+}
+
+func newResponse(resp *http.Response, body []byte) *response {
+	return &response{status: resp.StatusCode, header: resp.Header, body: body, baseline: -1}
+}
+
+// Условие над ответом
+type matcher interface {
+	match(r *response) bool
+}
+
+// body:ТЕКСТ — тело содержит подстроку
+type bodyContains string
+
+func (m bodyContains) match(r *response) bool {
+	return bytes.Contains(r.body, []byte(m))
+}
+
+// regex:ВЫРАЖЕНИЕ — тело соответствует регулярному выражению
+type bodyRegexp struct{ re *regexp.Regexp }
+
+func (m bodyRegexp) match(r *response) bool {
+	return m.re.Match(r.body)
+}
+
+// status:200,301-303 — код ответа входит в список или диапазон
+type statusIn [][2]int
+
+func (m statusIn) match(r *response) bool {
+	for _, rng := range m {
+		if r.status >= rng[0] && r.status <= rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// location:ВЫРАЖЕНИЕ — заголовок Location соответствует регулярному выражению
+type locationRegexp struct{ re *regexp.Regexp }
+
+func (m locationRegexp) match(r *response) bool {
+	loc := r.header.Get("Location")
+	return loc != "" && m.re.MatchString(loc)
+}
+
+// cookie:ИМЯ — ответ устанавливает cookie с этим именем (cookie:* — любую)
+type setsCookie string
+
+func (m setsCookie) match(r *response) bool {
+	for _, line := range r.header.Values("Set-Cookie") {
+		name, _, _ := strings.Cut(line, "=")
+		if m == "*" || strings.TrimSpace(name) == string(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// json:ПУТЬ — тело в формате JSON и содержит непустое поле, например json:data.token
+type jsonField string
+
+func (m jsonField) match(r *response) bool {
+	_, err := jsonLookup(r.body, string(m))
+	return err == nil
+}
+
+// delta:N — длина ответа отличается от базовой больше чем на N байт
+type lengthDelta int
+
+func (m lengthDelta) match(r *response) bool {
+	if r.baseline < 0 {
+		return false
+	}
+	d := len(r.body) - r.baseline
+	return d > int(m) || -d > int(m)
+}
+
+type notMatcher struct{ m matcher }
+
+func (n notMatcher) match(r *response) bool { return !n.m.match(r) }
+
+type andMatcher []matcher
+
+func (a andMatcher) match(r *response) bool {
+	for _, m := range a {
+		if !m.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type orMatcher []matcher
+
+func (o orMatcher) match(r *response) bool {
+	for _, m := range o {
+		if m.match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Условие использует длину базового ответа
+func usesBaseline(m matcher) bool {
+	switch m := m.(type) {
+	case lengthDelta:
+		return true
+	case notMatcher:
+		return usesBaseline(m.m)
+	case andMatcher:
+		for _, sub := range m {
+			if usesBaseline(sub) {
+				return true
+			}
+		}
+	case orMatcher:
+		for _, sub := range m {
+			if usesBaseline(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Разбор выражения условий. Простое условие записывается как вид:аргумент,
+// аргумент с пробелами берётся в двойные кавычки. Условия объединяются
+// операторами & (и), | (или), ! (не) и скобками, & связывает сильнее |.
+// Пример: status:200 & !body:"Username and/or password incorrect"
+func parseMatcher(expr string) (matcher, error) {
+	p := &matcherParser{src: expr}
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("условие %q: %w", expr, err)
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("условие %q: лишний текст с позиции %d", expr, p.pos)
+	}
+	return m, nil
+}
+
+type matcherParser struct {
+	src string
+	pos int
+}
+
+func (p *matcherParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *matcherParser) peek(c byte) bool {
+	p.skipSpace()
+	return p.pos < len(p.src) && p.src[p.pos] == c
+}
+
+func (p *matcherParser) parseOr() (matcher, error) {
+	var terms orMatcher
+	for {
+		m, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, m)
+		if !p.peek('|') {
+			break
+		}
+		p.pos++
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *matcherParser) parseAnd() (matcher, error) {
+	var terms andMatcher
+	for {
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, m)
+		if !p.peek('&') {
+			break
+		}
+		p.pos++
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *matcherParser) parseUnary() (matcher, error) {
+	switch {
+	case p.peek('!'):
+		p.pos++
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notMatcher{m}, nil
+	case p.peek('('):
+		p.pos++
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(')') {
+			return nil, fmt.Errorf("нет закрывающей скобки на позиции %d", p.pos)
+		}
+		p.pos++
+		return m, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *matcherParser) parseTerm() (matcher, error) {
+	p.skipSpace()
+	colon := strings.IndexByte(p.src[p.pos:], ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("ожидалось условие вида вид:аргумент на позиции %d", p.pos)
+	}
+	kind := p.src[p.pos : p.pos+colon]
+	p.pos += colon + 1
+	arg, err := p.parseArg()
+	if err != nil {
+		return nil, err
+	}
+	return newTerm(kind, arg)
+}
+
+// Аргумент: строка в двойных кавычках или слово до пробела, ) или конца
+func (p *matcherParser) parseArg() (string, error) {
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		end := p.pos + 1
+		for ; end < len(p.src); end++ {
+			if p.src[end] == '\\' {
+				end++
+				continue
+			}
+			if p.src[end] == '"' {
+				break
+			}
+		}
+		if end >= len(p.src) {
+			return "", fmt.Errorf("незакрытая кавычка на позиции %d", p.pos)
+		}
+		arg, err := strconv.Unquote(p.src[p.pos : end+1])
+		p.pos = end + 1
+		return arg, err
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos])) && p.src[p.pos] != ')' {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+func newTerm(kind, arg string) (matcher, error) {
+	switch kind {
+	case "body":
+		return bodyContains(arg), nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		return bodyRegexp{re}, err
+	case "status":
+		return parseStatusList(arg)
+	case "location":
+		re, err := regexp.Compile(arg)
+		return locationRegexp{re}, err
+	case "cookie":
+		return setsCookie(arg), nil
+	case "json":
+		return jsonField(arg), nil
+	case "delta":
+		n, err := strconv.Atoi(arg)
+		return lengthDelta(n), err
+	}
+	return nil, fmt.Errorf("неизвестный вид условия %q", kind)
+}
+
+// Список кодов: 200,301-303
+func parseStatusList(arg string) (matcher, error) {
+	var m statusIn
+	for _, part := range strings.Split(arg, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		m = append(m, [2]int{from, to})
+	}
+	return m, nil
+}
+
+// Правила классификации ответа. Проверяются по порядку: блокировка или
+// CAPTCHA, ошибка, успех, неудача. Если заданы правила и успеха, и неудачи, а ответ не подошёл
+// ни под одно, он считается ошибкой; если задано только одно из них,
+// неподходящий ответ относится к противоположному классу.
+type classifier struct {
+	lockout  matcher
+	captcha  matcher
+	errorM   matcher
+	success  matcher
+	failure  matcher
+	baseline int
+}
+
+// Выражения условий для классификатора
+type classifierRules struct {
+	success, failure, lockout, captcha, errorM string
+}
+
+// Правила по умолчанию — под ответы DVWA
+var defaultRules = classifierRules{
+	failure: `body:"Username and/or password incorrect"`,
+	lockout: `body:"has been locked" | status:429`,
+	captcha: `regex:"(?i)g-recaptcha|h-captcha|name=[\"']captcha[\"']"`,
+	errorM:  "!status:200",
+}
+
+func newClassifier(rules classifierRules) (*classifier, error) {
+	c := new(classifier)
+	for _, rule := range []struct {
+		dst  *matcher
+		expr string
+	}{
+		{&c.success, rules.success},
+		{&c.failure, rules.failure},
+		{&c.lockout, rules.lockout},
+		{&c.captcha, rules.captcha},
+		{&c.errorM, rules.errorM},
+	} {
+		var err error
+		if *rule.dst, err = parseMatcher(rule.expr); err != nil {
+			return nil, err
+		}
+	}
+	if c.success == nil && c.failure == nil {
+		return nil, errors.New("нужно задать условие успеха (-success) или неудачи (-failure)")
+	}
+	return c, nil
+}
+
+func (c *classifier) needsBaseline() bool {
+	for _, m := range []matcher{c.lockout, c.captcha, c.errorM, c.success, c.failure} {
+		if m != nil && usesBaseline(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *classifier) classify(r *response) (verdict, error) {
+	r.baseline = c.baseline
+	switch {
+	case c.lockout != nil && c.lockout.match(r), c.captcha != nil && c.captcha.match(r):
+		return verdictLockout, nil
+	case c.errorM != nil && c.errorM.match(r):
+		return verdictError, fmt.Errorf("status code = %v", r.status)
+	case c.success != nil && c.success.match(r):
+		return verdictSuccess, nil
+	case c.failure != nil && c.failure.match(r):
+		return verdictFailure, nil
+	case c.success != nil && c.failure != nil:
+		return verdictError, fmt.Errorf("ответ не подходит ни под успех, ни под неудачу (status code = %v, длина %d)", r.status, len(r.body))
+	case c.success != nil:
+		return verdictFailure, nil
+	default:
+		return verdictSuccess, nil
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+
+	"cracker/mockdvwa"
+)
+
+// Правило CAPTCHA по умолчанию срабатывает на виджеты CAPTCHA,
+// но не на пункт Insecure CAPTCHA в меню DVWA
+func TestDefaultCaptchaRule(t *testing.T) {
+	cls, err := newClassifier(defaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := mockdvwa.NewTestServer(mockdvwa.Config{Security: "low"})
+	defer srv.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	// Вход в имитацию, чтобы получить страницу Brute Force с меню
+	resp, err := client.Get(srv.URL + mockdvwa.LoginPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	token := hiddenInputRegexp("user_token").FindSubmatch(body)
+	if token == nil {
+		t.Fatal("на странице входа нет user_token")
+	}
+	resp, err = client.PostForm(srv.URL+mockdvwa.LoginPath, url.Values{
+		"username": {"admin"}, "password": {mockdvwa.DefaultUsers["admin"]}, "user_token": {string(token[1])},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = client.Get(srv.URL + mockdvwa.BrutePath + "?username=admin&password=wrong&Login=Login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "Insecure CAPTCHA") {
+		t.Fatal("на странице Brute Force нет меню DVWA")
+	}
+
+	for _, c := range []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"страница DVWA с меню", string(body), false},
+		{"reCAPTCHA", `<div class="g-recaptcha" data-sitekey="x"></div>`, true},
+		{"hCaptcha", `<div class="h-captcha" data-sitekey="x"></div>`, true},
+		{"поле captcha", `<input type="text" name='captcha'>`, true},
+	} {
+		r := &response{status: http.StatusOK, body: []byte(c.body), baseline: -1}
+		if got := cls.captcha.match(r); got != c.want {
+			t.Errorf("%s: CAPTCHA %v, ожидалось %v", c.name, got, c.want)
+		}
+	}
+}
+
+// Ответ с заданным кодом и телом; базовая длина не измерялась
+func testResponse(status int, body string) *response {
+	return &response{status: status, header: http.Header{}, body: []byte(body), baseline: -1}
+}
+
+func TestParseMatcher(t *testing.T) {
+	withHeader := func(r *response, k, v string) *response {
+		r.header.Add(k, v)
+		return r
+	}
+	withBaseline := func(r *response, n int) *response {
+		r.baseline = n
+		return r
+	}
+	for _, c := range []struct {
+		expr string
+		resp *response
+		want bool
+	}{
+		// & связывает сильнее |: a | (b & c)
+		{"body:a | body:b & body:c", testResponse(200, "a"), true},
+		{"body:a | body:b & body:c", testResponse(200, "b"), false},
+		{"body:a | body:b & body:c", testResponse(200, "bc"), true},
+		{"(body:a | body:b) & body:c", testResponse(200, "a"), false},
+		{"(body:a | body:b) & body:c", testResponse(200, "bc"), true},
+		// ! относится к ближайшему условию или скобке
+		{"!body:a & body:b", testResponse(200, "b"), true},
+		{"!body:a & body:b", testResponse(200, "ab"), false},
+		{"!(body:a & body:b)", testResponse(200, "a"), true},
+		{"!!body:a", testResponse(200, "a"), true},
+		{"  ( ( body:a ) )  ", testResponse(200, "a"), true},
+		{`body:"two words" & !body:"say \"no\""`, testResponse(200, "two words"), true},
+		{`body:"two words" & !body:"say \"no\""`, testResponse(200, `two words, say "no"`), false},
+		{`regex:^o+k$`, testResponse(200, "oook"), true},
+		{"status:200,301-303", testResponse(302, ""), true},
+		{"status:200,301-303", testResponse(304, ""), false},
+		{"location:index\\.php", withHeader(testResponse(302, ""), "Location", "/dvwa/index.php"), true},
+		{"location:.*", testResponse(302, ""), false},
+		{"cookie:PHPSESSID", withHeader(testResponse(200, ""), "Set-Cookie", "PHPSESSID=1; path=/"), true},
+		{"cookie:PHPSESSID", withHeader(testResponse(200, ""), "Set-Cookie", "security=low"), false},
+		{"cookie:*", withHeader(testResponse(200, ""), "Set-Cookie", "security=low"), true},
+		{"json:data.token", testResponse(200, `{"data": {"token": "t"}}`), true},
+		{"json:data.token", testResponse(200, `{"data": {"token": ""}}`), false},
+		// delta сравнивает длину с базовой, пока её нет — не срабатывает
+		{"delta:3", testResponse(200, "1234567890"), false},
+		{"delta:3", withBaseline(testResponse(200, "1234567890"), 6), true},
+		{"delta:3", withBaseline(testResponse(200, "1234567890"), 7), false},
+		{"delta:3", withBaseline(testResponse(200, "12"), 6), true},
+	} {
+		m, err := parseMatcher(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if got := m.match(c.resp); got != c.want {
+			t.Errorf("%s на %q: %v, ожидалось %v", c.expr, c.resp.body, got, c.want)
+		}
+	}
+}
+
+func TestParseMatcherErrors(t *testing.T) {
+	for _, expr := range []string{
+		"(body:a | body:b",
+		"body:a)",
+		"body:a body:b",
+		"body:a &",
+		"| body:a",
+		"!",
+		"size:10",
+		"status",
+		`body:"open`,
+		"status:2xx",
+		"status:200-abc",
+		"regex:(",
+		"location:[",
+		"delta:many",
+	} {
+		if m, err := parseMatcher(expr); err == nil {
+			t.Errorf("%s: разобрано как %#v, ожидалась ошибка", expr, m)
+		}
+	}
+	if m, err := parseMatcher("  "); m != nil || err != nil {
+		t.Errorf("пустое условие: %#v, %v; ожидалось отсутствие условия", m, err)
+	}
+}
+
+func TestUsesBaseline(t *testing.T) {
+	for _, c := range []struct {
+		expr string
+		want bool
+	}{
+		{"delta:10", true},
+		{"status:200 & !delta:10", true},
+		{"body:a | (status:200 & delta:5)", true},
+		{"body:a | !status:200", false},
+	} {
+		m, err := parseMatcher(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := usesBaseline(m); got != c.want {
+			t.Errorf("%s: %v, ожидалось %v", c.expr, got, c.want)
+		}
+	}
+}
+
+// Правила проверяются по порядку: блокировка и CAPTCHA, ошибка, успех,
+// неудача; неподходящий ответ зависит от того, какие правила заданы
+func TestClassify(t *testing.T) {
+	full := classifierRules{
+		success: "body:welcome",
+		failure: "body:incorrect",
+		lockout: "body:locked | status:429",
+		captcha: "body:captcha",
+		errorM:  "status:500-599",
+	}
+	for _, c := range []struct {
+		name  string
+		rules classifierRules
+		resp  *response
+		want  verdict
+	}{
+		{"успех", full, testResponse(200, "welcome"), verdictSuccess},
+		{"неудача", full, testResponse(200, "incorrect"), verdictFailure},
+		{"блокировка важнее успеха", full, testResponse(200, "welcome, account locked"), verdictLockout},
+		{"блокировка важнее ошибки", full, testResponse(429, "incorrect"), verdictLockout},
+		{"CAPTCHA считается защитой", full, testResponse(200, "incorrect, solve captcha"), verdictLockout},
+		{"ошибка важнее успеха", full, testResponse(502, "welcome"), verdictError},
+		{"успех важнее неудачи", full, testResponse(200, "welcome back, incorrect hint"), verdictSuccess},
+		{"ни успех, ни неудача", full, testResponse(200, "maintenance"), verdictError},
+		{"задан только успех", classifierRules{success: "body:welcome"}, testResponse(200, "maintenance"), verdictFailure},
+		{"задана только неудача", classifierRules{failure: "body:incorrect"}, testResponse(200, "maintenance"), verdictSuccess},
+		{"правила DVWA: неверный пароль", defaultRules, testResponse(200, "Username and/or password incorrect."), verdictFailure},
+		{"правила DVWA: вход", defaultRules, testResponse(200, "Welcome to the password protected area admin"), verdictSuccess},
+		{"правила DVWA: блокировка", defaultRules, testResponse(200, "This account has been locked"), verdictLockout},
+		{"правила DVWA: перенаправление", defaultRules, testResponse(302, ""), verdictError},
+	} {
+		cls, err := newClassifier(c.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := cls.classify(c.resp); got != c.want {
+			t.Errorf("%s: %v, ожидалось %v", c.name, got, c.want)
+		}
+	}
+}
+
+// Условие по отклонению длины сравнивается с базовой длиной классификатора
+func TestClassifyBaseline(t *testing.T) {
+	cls, err := newClassifier(classifierRules{success: "delta:20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cls.needsBaseline() {
+		t.Fatal("классификатору с delta не нужна базовая длина")
+	}
+	cls.baseline = 100
+	for _, c := range []struct {
+		length int
+		want   verdict
+	}{
+		{100, verdictFailure},
+		{115, verdictFailure},
+		{130, verdictSuccess},
+		{60, verdictSuccess},
+	} {
+		if got, _ := cls.classify(testResponse(200, strings.Repeat("x", c.length))); got != c.want {
+			t.Errorf("длина %d: %v, ожидалось %v", c.length, got, c.want)
+		}
+	}
+	if _, err := newClassifier(classifierRules{lockout: "status:429"}); err == nil {
+		t.Error("классификатор без условий успеха и неудачи создан без ошибки")
+	}
+}
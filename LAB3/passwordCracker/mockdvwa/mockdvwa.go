@@ -0,0 +1,318 @@
+// Пакет mockdvwa — имитация DVWA для проверки переборщика без установленного
+// DVWA: страница входа, сессия с анти-CSRF токеном и страница Brute Force
+// (/dvwa/vulnerabilities/brute/) с поведением уровней low, medium, high и impossible.
+package mockdvwa
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Пути страниц, как в DVWA
+const (
+	LoginPath = "/dvwa/login.php"
+	IndexPath = "/dvwa/index.php"
+	BrutePath = "/dvwa/vulnerabilities/brute/"
+)
+
+// Сообщения страницы Brute Force
+const (
+	WelcomeMessage = "Welcome to the password protected area"
+	FailureMessage = "Username and/or password incorrect."
+	LockedMessage  = "Alternative, the account has been locked because of too many failed logins."
+	CSRFMessage    = "CSRF token is incorrect."
+)
+
+// DefaultUsers — учётные записи DVWA по умолчанию
+var DefaultUsers = map[string]string{
+	"admin":   "password",
+	"gordonb": "abc123",
+	"1337":    "charley",
+	"pablo":   "letmein",
+	"smithy":  "password",
+}
+
+// Config — настройки имитации. Нулевые значения означают поведение DVWA
+// по умолчанию, кроме TimeScale: при нуле задержек нет.
+type Config struct {
+	Users           map[string]string // логин -> пароль, по умолчанию DefaultUsers
+	Security        string            // уровень защиты после входа, по умолчанию impossible
+	TimeScale       float64           // множитель задержек DVWA: 1 — как в DVWA, 0 — без задержек
+	LockoutAttempts int               // неудачных попыток до блокировки на уровне impossible, по умолчанию 3
+	LockoutTime     time.Duration     // длительность блокировки, по умолчанию 15 минут
+	Seed            uint64            // seed для случайных задержек
+}
+
+// Состояние сессии PHPSESSID
+type session struct {
+	loggedIn bool
+	token    string // текущий анти-CSRF токен, меняется при каждой выдаче формы
+}
+
+// Состояние учётной записи для уровня impossible
+type account struct {
+	failed    int
+	lastLogin time.Time
+}
+
+// Server — обработчик HTTP, имитирующий DVWA
+type Server struct {
+	cfg      Config
+	mu       sync.Mutex
+	rng      *mrand.Rand
+	sessions map[string]*session
+	accounts map[string]*account
+}
+
+// New создаёт имитацию DVWA с настройками cfg
+func New(cfg Config) *Server {
+	if cfg.Users == nil {
+		cfg.Users = DefaultUsers
+	}
+	if cfg.Security == "" {
+		cfg.Security = "impossible"
+	}
+	if cfg.LockoutAttempts == 0 {
+		cfg.LockoutAttempts = 3
+	}
+	if cfg.LockoutTime == 0 {
+		cfg.LockoutTime = 15 * time.Minute
+	}
+	return &Server{
+		cfg:      cfg,
+		rng:      mrand.New(mrand.NewPCG(cfg.Seed, cfg.Seed)),
+		sessions: make(map[string]*session),
+		accounts: make(map[string]*account),
+	}
+}
+
+// NewTestServer запускает имитацию на httptest.Server для сквозных проверок.
+// Адрес страницы Brute Force: srv.URL + BrutePath.
+func NewTestServer(cfg Config) *httptest.Server {
+	return httptest.NewServer(New(cfg))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case LoginPath:
+		s.login(w, r)
+	case IndexPath:
+		if _, ok := s.loggedIn(w, r); ok {
+			fmt.Fprint(w, appPage("Welcome", "<h1>Welcome to Damn Vulnerable Web Application!</h1>"))
+		}
+	case BrutePath:
+		s.brute(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Сессия запроса; если cookie PHPSESSID нет или она неизвестна, создаётся новая
+func (s *Server) session(w http.ResponseWriter, r *http.Request) *session {
+	if c, err := r.Cookie("PHPSESSID"); err == nil {
+		if sess, ok := s.sessions[c.Value]; ok {
+			return sess
+		}
+	}
+	id := randomHex(13)
+	sess := new(session)
+	s.sessions[id] = sess
+	http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: id, Path: "/"})
+	return sess
+}
+
+// Новый токен формы, как generateSessionToken в DVWA
+func (sess *session) newToken() string {
+	sess.token = randomHex(16)
+	return sess.token
+}
+
+func tokenField(token string) string {
+	return "<input type='hidden' name='user_token' value='" + token + "' />"
+}
+
+func page(title, body string) string {
+	return "<!DOCTYPE html><html><head><title>" + title + " :: Damn Vulnerable Web Application (DVWA)</title></head>" +
+		"<body><div id=\"main_body\">" + body + "</div></body></html>"
+}
+
+// Левое меню DVWA, которое есть на каждой странице после входа.
+// Пункт Insecure CAPTCHA в нём есть всегда, хотя CAPTCHA на странице нет.
+const menu = "<div id=\"main_menu\"><div id=\"main_menu_padded\"><ul class=\"menuBlocks\">" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/brute/\">Brute Force</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/exec/\">Command Injection</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/csrf/\">CSRF</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/fi/.?page=include.php\">File Inclusion</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/upload/\">File Upload</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/captcha/\">Insecure CAPTCHA</a></li>" +
+	"<li class=\"\"><a href=\"../../vulnerabilities/sqli/\">SQL Injection</a></li>" +
+	"</ul></div></div>"
+
+// Страница приложения после входа: с меню
+func appPage(title, body string) string {
+	return page(title, menu+body)
+}
+
+// Проверка входа; если сессии нет, перенаправление на страницу входа
+func (s *Server) loggedIn(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.session(w, r)
+	if !sess.loggedIn {
+		http.Redirect(w, r, LoginPath, http.StatusFound)
+		return nil, false
+	}
+	return sess, true
+}
+
+// Страница входа в приложение
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.session(w, r)
+
+	if r.Method == http.MethodPost {
+		valid := r.PostFormValue("user_token") == sess.token && sess.token != ""
+		pass, ok := s.cfg.Users[r.PostFormValue("username")]
+		if valid && ok && pass == r.PostFormValue("password") {
+			sess.loggedIn = true
+			http.SetCookie(w, &http.Cookie{Name: "security", Value: s.cfg.Security, Path: "/"})
+			http.Redirect(w, r, IndexPath, http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, LoginPath, http.StatusFound)
+		return
+	}
+
+	form := "<form action=\"login.php\" method=\"post\">" +
+		"<input type=\"text\" name=\"username\"><input type=\"password\" name=\"password\">" +
+		"<input type=\"submit\" value=\"Login\" name=\"Login\">" + tokenField(sess.newToken()) + "</form>"
+	fmt.Fprint(w, page("Login", form))
+}
+
+// Случайная задержка от lo до hi секунд с учётом TimeScale
+func (s *Server) delay(lo, hi int) time.Duration {
+	s.mu.Lock()
+	secs := lo + s.rng.IntN(hi-lo+1)
+	s.mu.Unlock()
+	return time.Duration(float64(secs) * s.cfg.TimeScale * float64(time.Second))
+}
+
+// Страница Brute Force. Поведение уровней повторяет исходный код DVWA:
+//   - low: проверка логина и пароля без задержек;
+//   - medium: при неудаче sleep(2);
+//   - high: проверка user_token, при неудаче sleep(rand(0, 3));
+//   - impossible: POST с user_token, блокировка после LockoutAttempts неудач
+//     на LockoutTime, при неудаче sleep(rand(2, 4)), а сообщение о неудаче
+//     одинаково для неверного пароля и заблокированной учётной записи.
+func (s *Server) brute(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.loggedIn(w, r)
+	if !ok {
+		return
+	}
+	security := "impossible"
+	if c, err := r.Cookie("security"); err == nil {
+		security = c.Value
+	}
+
+	form := r.URL.Query()
+	if security == "impossible" {
+		r.ParseForm()
+		form = r.PostForm
+	}
+	if !form.Has("Login") {
+		s.mu.Lock()
+		token := sess.newToken()
+		s.mu.Unlock()
+		fmt.Fprint(w, brutePage("", token))
+		return
+	}
+
+	s.mu.Lock()
+	wantToken := sess.token
+	token := sess.newToken()
+	s.mu.Unlock()
+	if (security == "high" || security == "impossible") && form.Get("user_token") != wantToken {
+		// checkToken в DVWA: сообщение и перенаправление на index.php
+		http.Redirect(w, r, IndexPath, http.StatusFound)
+		fmt.Fprint(w, CSRFMessage)
+		return
+	}
+
+	user, pass := form.Get("username"), form.Get("password")
+	want, exists := s.cfg.Users[user]
+	success := exists && want == pass
+
+	var message string
+	switch security {
+	case "impossible":
+		success = s.checkLockout(user, exists, success)
+		if !success {
+			time.Sleep(s.delay(2, 4))
+			message = "<pre><br />" + FailureMessage + "<br /><br/>" + LockedMessage +
+				"<br />If this is the case, <em>please try again in 15 minutes</em>.</pre>"
+		}
+	case "high":
+		if !success {
+			time.Sleep(s.delay(0, 3))
+		}
+	case "medium":
+		if !success {
+			time.Sleep(s.delay(2, 2))
+		}
+	}
+	switch {
+	case success:
+		message = "<p>" + WelcomeMessage + " " + html.EscapeString(user) + "</p><img src=\"/dvwa/hackable/users/" + html.EscapeString(user) + ".jpg\" />"
+	case message == "":
+		message = "<pre><br />" + FailureMessage + "</pre>"
+	}
+	fmt.Fprint(w, brutePage(message, token))
+}
+
+// Учёт попытки на уровне impossible. Возвращает итог входа с учётом блокировки.
+// Как и в DVWA, неудачные попытки считаются и во время блокировки.
+func (s *Server) checkLockout(user string, exists, success bool) bool {
+	if !exists {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.accounts[user]
+	if !ok {
+		acc = new(account)
+		s.accounts[user] = acc
+	}
+	now := time.Now()
+	if acc.failed >= s.cfg.LockoutAttempts && now.Before(acc.lastLogin.Add(s.cfg.LockoutTime)) {
+		success = false
+	}
+	if success {
+		acc.failed = 0
+	} else {
+		acc.failed++
+	}
+	acc.lastLogin = now
+	return success
+}
+
+func brutePage(message, token string) string {
+	form := "<h1>Vulnerability: Brute Force</h1><form action=\"#\" method=\"GET\">" +
+		"Username:<br /><input type=\"text\" name=\"username\"><br />" +
+		"Password:<br /><input type=\"password\" AUTOCOMPLETE=\"off\" name=\"password\"><br />" +
+		"<input type=\"submit\" value=\"Login\" name=\"Login\">" + tokenField(token) + "</form>" + message
+	return appPage("Vulnerability: Brute Force", form)
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Протоколы аутентификации
+const (
+	protocolForm    = "form"    // HTML-форма по шаблону запроса, как в DVWA
+	protocolBasic   = "basic"   // HTTP Basic (RFC 7617)
+	protocolDigest  = "digest"  // HTTP Digest (RFC 7616)
+	protocolJSON    = "json"    // JSON API, выдающее bearer-токен
+	protocolAuthSys = "authsys" // /login из LAB3/authSystem
+)
+
+// Выполнение одной попытки входа по протоколу. Тело ответа закрывает вызывающий.
+type authenticator interface {
+	attempt(ctx context.Context, login, pass string) (*http.Response, error)
+}
+
+// Настройки протоколов, кроме формы: у неё своя сессия
+type protocolConfig struct {
+	Target    *url.URL
+	Transport http.RoundTripper // nil — http.DefaultTransport
+	UserField string            // поле логина в JSON-запросе
+	PassField string            // поле пароля в JSON-запросе
+	AuthToken string            // значение параметра token для authsys
+}
+
+func newAuthenticator(name string, cfg protocolConfig) (authenticator, error) {
+	client := &http.Client{
+		Transport: cfg.Transport,
+		// Ответ на попытку проверяется как есть, включая Location
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	switch name {
+	case protocolBasic:
+		return &basicAuth{client: client, target: cfg.Target}, nil
+	case protocolDigest:
+		return &digestAuth{client: client, target: cfg.Target}, nil
+	case protocolJSON:
+		return &jsonLogin{client: client, target: cfg.Target, userField: cfg.UserField, passField: cfg.PassField}, nil
+	case protocolAuthSys:
+		return &authSysLogin{client: client, target: cfg.Target, token: cfg.AuthToken}, nil
+	}
+	return nil, fmt.Errorf("неизвестный протокол %q: form, basic, digest, json или authsys", name)
+}
+
+// Правила классификации по умолчанию для протокола. tokenField — поле
+// ответа JSON API с токеном: его наличие означает успешный вход.
+func protocolRules(name, tokenField string) classifierRules {
+	captcha := defaultRules.captcha
+	switch name {
+	case protocolBasic, protocolDigest:
+		return classifierRules{success: "status:200-399", failure: "status:401", lockout: "status:429", captcha: captcha}
+	case protocolJSON:
+		// Ответ 200 без токена — тоже неудача, например второй фактор
+		return classifierRules{success: "json:" + tokenField, failure: "status:200,400-403", lockout: "status:429", captcha: captcha}
+	case protocolAuthSys:
+		// 404 — нет такого пользователя, 401 — неверный пароль или токен,
+		// 423 — учётная запись заблокирована
+		return classifierRules{success: "status:200", failure: "status:401,404", lockout: "status:423,429"}
+	}
+	return defaultRules
+}
+
+// HTTP Basic: логин и пароль в заголовке Authorization каждого запроса
+type basicAuth struct {
+	client *http.Client
+	target *url.URL
+}
+
+func (a *basicAuth) attempt(ctx context.Context, login, pass string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(login, pass)
+	return a.client.Do(req)
+}
+
+// Вызов Digest из заголовка WWW-Authenticate
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm string
+	qop                             string // auth, если сервер его предлагает, иначе пусто
+	stale                           bool
+}
+
+// Разбор вызова Digest. Заголовков WWW-Authenticate может быть несколько,
+// по одному на схему.
+func parseDigestChallenge(h http.Header) (*digestChallenge, bool) {
+	for _, v := range h.Values("WWW-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			stale:     strings.EqualFold(params["stale"], "true"),
+		}
+		for _, q := range strings.Split(params["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				c.qop = "auth"
+			}
+		}
+		if c.nonce == "" {
+			continue
+		}
+		return c, true
+	}
+	return nil, false
+}
+
+// Параметры вида ключ=значение или ключ="значение", через запятую
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " \t,") {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value, s = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// HTTP Digest. Вызов сервера запоминается и используется для следующих
+// попыток со счётчиком nc, поэтому лишний запрос за вызовом нужен только
+// в начале и когда сервер отвечает, что nonce устарел (stale=true).
+type digestAuth struct {
+	client *http.Client
+	target *url.URL
+
+	mu   sync.Mutex
+	chal *digestChallenge
+	nc   int
+}
+
+// Текущий вызов и очередное значение счётчика
+func (a *digestAuth) challenge(ctx context.Context) (*digestChallenge, int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chal == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.target.String(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		resp, err := a.client.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		c, ok := parseDigestChallenge(resp.Header)
+		if !ok {
+			return nil, 0, fmt.Errorf("сервер не запросил Digest-аутентификацию (status code = %v)", resp.StatusCode)
+		}
+		a.chal, a.nc = c, 0
+	}
+	a.nc++
+	return a.chal, a.nc, nil
+}
+
+// Замена вызова новым из ответа сервера. С прежним nonce счётчик
+// продолжается: сервер может отвергать повторные значения nc.
+func (a *digestAuth) update(old, c *digestChallenge) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chal == old && c.nonce != old.nonce {
+		a.chal, a.nc = c, 0
+	}
+}
+
+func (a *digestAuth) attempt(ctx context.Context, login, pass string) (*http.Response, error) {
+	const maxStale = 3
+	for i := 0; ; i++ {
+		c, nc, err := a.challenge(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		auth, err := digestAuthorization(c, req.Method, req.URL.RequestURI(), login, pass, nc)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", auth)
+		resp, err := a.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		next, ok := parseDigestChallenge(resp.Header)
+		if resp.StatusCode != http.StatusUnauthorized || !ok {
+			return resp, nil
+		}
+		a.update(c, next)
+		if !next.stale || i == maxStale {
+			return resp, nil
+		}
+		// Пароль не проверялся: nonce устарел, попытка повторяется с новым
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// Заголовок Authorization для ответа на вызов c
+func digestAuthorization(c *digestChallenge, method, uri, login, pass string, nc int) (string, error) {
+	algorithm := strings.ToUpper(c.algorithm)
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("алгоритм Digest %q не поддерживается", c.algorithm)
+	}
+	h := func(parts ...string) string {
+		d := newHash()
+		io.WriteString(d, strings.Join(parts, ":"))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	random := make([]byte, 8)
+	rand.Read(random)
+	cnonce := hex.EncodeToString(random)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(login, c.realm, pass)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	ha2 := h(method, uri)
+	var response string
+	if c.qop != "" {
+		response = h(ha1, c.nonce, ncValue, cnonce, c.qop, ha2)
+	} else {
+		response = h(ha1, c.nonce, ha2)
+	}
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	fields := []string{
+		fmt.Sprintf(`username="%s"`, quote(login)),
+		fmt.Sprintf(`realm="%s"`, quote(c.realm)),
+		fmt.Sprintf(`nonce="%s"`, quote(c.nonce)),
+		fmt.Sprintf(`uri="%s"`, quote(uri)),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if c.algorithm != "" {
+		fields = append(fields, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		fields = append(fields, fmt.Sprintf(`opaque="%s"`, quote(c.opaque)))
+	}
+	if c.qop != "" {
+		fields = append(fields, "qop="+c.qop, "nc="+ncValue, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// Вход через JSON API: POST {"<userField>": логин, "<passField>": пароль},
+// при успехе сервер возвращает токен для заголовка Authorization: Bearer
+type jsonLogin struct {
+	client               *http.Client
+	target               *url.URL
+	userField, passField string
+}
+
+func (a *jsonLogin) attempt(ctx context.Context, login, pass string) (*http.Response, error) {
+	body, err := json.Marshal(map[string]string{a.userField: login, a.passField: pass})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.target.String(), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return a.client.Do(req)
+}
+
+// Вход в LAB3/authSystem: форма username, password и token на /login.
+// Токен пользователя сервер генерирует сам, поэтому значение задаётся флагом.
+type authSysLogin struct {
+	client *http.Client
+	target *url.URL
+	token  string
+}
+
+func (a *authSysLogin) attempt(ctx context.Context, login, pass string) (*http.Response, error) {
+	form := url.Values{"username": {login}, "password": {pass}, "token": {a.token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.target.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return a.client.Do(req)
+}
+
+// Значение по пути вида data.token в JSON-документе: ключи объектов
+// через точку. Пустые строки, null и false значением не считаются.
+func jsonLookup(body []byte, path string) (any, error) {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, errors.New("нет поля " + path)
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, errors.New("нет поля " + path)
+		}
+	}
+	switch v {
+	case nil, "", false:
+		return nil, errors.New("пустое поле " + path)
+	}
+	return v, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"cracker/mockdvwa"
+)
+
+// Словарь для сквозных проверок: пароли трёх пользователей DVWA
+// и логин, которого нет
+func testLists() *attackLists {
+	return &attackLists{
+		users:     []string{"admin", "gordonb", "pablo", "nobody"},
+		passwords: []string{"123456", "abc123", "qwerty", "letmein", "password"},
+	}
+}
+
+// Пары, которые должны найтись по testLists
+var testWant = map[string]string{"admin": "password", "gordonb": "abc123", "pablo": "letmein"}
+
+// Перебор по протоколу с его правилами по умолчанию
+func attackProtocol(ctx context.Context, target, protocol string, lists *attackLists) (map[string]string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := newAuthenticator(protocol, protocolConfig{Target: u, UserField: "username", PassField: "password"})
+	if err != nil {
+		return nil, err
+	}
+	cls, err := newClassifier(protocolRules(protocol, "access_token"))
+	if err != nil {
+		return nil, err
+	}
+	return collectFound(ctx, 4, strategyClusterBomb, lists, loginAttempt(auth, cls), nil)
+}
+
+// Перебор по каждому протоколу входа на локальном сервере
+func TestProtocols(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		protocol string
+		start    func(map[string]string) *httptest.Server
+		path     string
+	}{
+		{"HTTP Basic", protocolBasic, startBasicServer, "/"},
+		{"HTTP Digest", protocolDigest, startDigestServer, "/protected"},
+		{"JSON API с bearer-токеном", protocolJSON, startJSONServer, "/api/login"},
+		{"authSystem /login", protocolAuthSys, startAuthSysServer, "/login"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			srv := c.start(mockdvwa.DefaultUsers)
+			defer srv.Close()
+			found, err := attackProtocol(context.Background(), srv.URL+c.path, c.protocol, testLists())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(found, testWant) {
+				t.Errorf("найдено %v вместо %v", found, testWant)
+			}
+		})
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		header []string
+		want   *digestChallenge
+	}{
+		{
+			"SHA-256 с qop",
+			[]string{`Digest realm="lab 3", qop="auth,auth-int", algorithm=SHA-256, nonce="abc", opaque="xyz"`},
+			&digestChallenge{realm: "lab 3", nonce: "abc", opaque: "xyz", algorithm: "SHA-256", qop: "auth"},
+		},
+		{
+			"после Basic, stale и экранирование",
+			[]string{`Basic realm="x"`, `Digest realm="a \"b\"", nonce="n1", stale=TRUE`},
+			&digestChallenge{realm: `a "b"`, nonce: "n1", stale: true},
+		},
+		{"без nonce", []string{`Digest realm="x"`}, nil},
+		{"только Basic", []string{`Basic realm="x"`}, nil},
+	} {
+		h := http.Header{"Www-Authenticate": c.header}
+		got, ok := parseDigestChallenge(h)
+		switch {
+		case c.want == nil && ok:
+			t.Errorf("%s: разобран вызов %+v, ожидалось отсутствие", c.name, *got)
+		case c.want != nil && !ok:
+			t.Errorf("%s: вызов не разобран", c.name)
+		case c.want != nil && *got != *c.want:
+			t.Errorf("%s: %+v, ожидалось %+v", c.name, *got, *c.want)
+		}
+	}
+}
+
+func TestJSONLookup(t *testing.T) {
+	body := []byte(`{"access_token": "t1", "data": {"token": "t2", "empty": "", "ok": false}, "list": [1]}`)
+	for _, c := range []struct {
+		path string
+		want any
+	}{
+		{"access_token", "t1"},
+		{"data.token", "t2"},
+		{"data.empty", nil},
+		{"data.ok", nil},
+		{"data.missing", nil},
+		{"list.0", nil},
+	} {
+		got, err := jsonLookup(body, c.path)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("%s: %v, ожидалась ошибка", c.path, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: %v, %v; ожидалось %v", c.path, got, err, c.want)
+		}
+	}
+}
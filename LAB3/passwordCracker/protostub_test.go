@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Серверы для проверки протоколов входа. Учётные данные — users, логин -> пароль.
+
+// HTTP Basic: 200 для верной пары, 401 с вызовом Basic для остальных
+func startBasicServer(users map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login, pass, ok := r.BasicAuth()
+		if want, known := users[login]; !ok || !known || pass != want {
+			w.Header().Set("WWW-Authenticate", `Basic realm="lab3"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "hello, %s\n", login)
+	}))
+}
+
+// HTTP Digest с SHA-256 и qop=auth. Nonce меняется каждые nonceUses ответов
+// на верные и неверные попытки, на старый nonce сервер отвечает stale=true.
+// Ответ считается независимо от digestAuthorization, чтобы проверка
+// не повторяла ошибки клиента.
+func startDigestServer(users map[string]string) *httptest.Server {
+	const (
+		realm     = "lab3 digest"
+		nonceUses = 5
+	)
+	var mu sync.Mutex
+	var nonce string
+	uses := 0
+	newNonce := func() {
+		b := make([]byte, 12)
+		rand.Read(b)
+		nonce, uses = hex.EncodeToString(b), 0
+	}
+	newNonce()
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		challenge := func(stale bool) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Digest realm="%s", qop="auth", algorithm=SHA-256, nonce="%s", opaque="lab3", stale=%t`, realm, nonce, stale))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+
+		scheme, rest, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if scheme != "Digest" {
+			challenge(false)
+			return
+		}
+		p := parseAuthParams(rest)
+		if p["nonce"] != nonce {
+			challenge(true)
+			return
+		}
+		if uses++; uses >= nonceUses {
+			newNonce()
+		}
+		pass, known := users[p["username"]]
+		ha1 := sum(p["username"] + ":" + realm + ":" + pass)
+		ha2 := sum(r.Method + ":" + p["uri"])
+		want := sum(strings.Join([]string{ha1, p["nonce"], p["nc"], p["cnonce"], "auth", ha2}, ":"))
+		if !known || p["qop"] != "auth" || p["uri"] != r.URL.RequestURI() || p["response"] != want {
+			challenge(false)
+			return
+		}
+		fmt.Fprintf(w, "hello, %s\n", p["username"])
+	}))
+}
+
+// JSON API: POST {"username", "password"}, при успехе {"access_token": ...}
+func startJSONServer(users map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var creds struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&creds) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad request"})
+			return
+		}
+		if want, ok := users[creds.Username]; !ok || creds.Password != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid credentials"})
+			return
+		}
+		b := make([]byte, 16)
+		rand.Read(b)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": hex.EncodeToString(b), "token_type": "Bearer"})
+	}))
+}
+
+// /login как в LAB3/authSystem: 404 — нет пользователя, 401 — неверный
+// пароль или токен, 200 — вход выполнен. Токены пользователей пустые.
+func startAuthSysServer(users map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		pass, ok := users[r.FormValue("username")]
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		case r.FormValue("password") != pass || r.FormValue("token") != "":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	return httptest.NewServer(mux)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cracker/mockdvwa"
+)
+
+// Перебор через прокси: HTTP, CONNECT к HTTPS и SOCKS5 вместе с недоступным
+// прокси, который должен быть исключён, не сорвав перебор
+func TestProxies(t *testing.T) {
+	httpProxy := startHTTPProxy()
+	defer httpProxy.Close()
+	socksProxy, err := startSOCKS5Proxy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socksProxy.Close()
+	plain := mockdvwa.NewTestServer(mockdvwa.Config{Security: "low", Seed: 1})
+	defer plain.Close()
+	secure := httptest.NewTLSServer(mockdvwa.New(mockdvwa.Config{Security: "low", Seed: 1}))
+	defer secure.Close()
+
+	for _, c := range []struct {
+		name    string
+		srvURL  string
+		proxies []string
+		stub    *stubProxy
+	}{
+		{"HTTP-прокси", plain.URL, []string{httpProxy.URL}, httpProxy},
+		{"HTTP-прокси, CONNECT к HTTPS", secure.URL, []string{httpProxy.URL}, httpProxy},
+		{"SOCKS5 и недоступный прокси", plain.URL, []string{"http://127.0.0.1:1", socksProxy.URL}, socksProxy},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			urls, err := parseProxies(c.proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pool, err := newProxyPool(urls, rotationRoundRobin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := c.stub.requests.Load()
+			found, _, err := attackMock(context.Background(), c.srvURL, "low", 4, testLists(), newTransport(pool, true, 0))
+			switch {
+			case err != nil:
+				t.Fatal(err)
+			case !maps.Equal(found, testWant):
+				t.Errorf("найдено %v вместо %v", found, testWant)
+			case c.stub.requests.Load() == before:
+				t.Error("запросы шли мимо прокси")
+			}
+		})
+	}
+}
+
+// Отменённые запросы, например попытки логина, пароль которого уже найден,
+// не должны исключать исправный прокси
+func TestProxyCancelledRequests(t *testing.T) {
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hang.Close()
+	u, err := url.Parse(hang.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := newProxyPool([]*url.URL{u}, rotationRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: newTransport(pool, false, 0)}
+
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://target.invalid/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			t.Fatal("запрос к зависшему прокси завершился без ошибки")
+		}
+		cancel()
+	}
+	for _, ps := range pool.stats() {
+		if ps.Failures != 0 || ps.Down {
+			t.Errorf("прокси %s: ошибок %d, исключён %v; отмена не должна считаться ошибкой прокси", ps.URL, ps.Failures, ps.Down)
+		}
+	}
+}
+
+// Прокси исключается после трёх ошибок подряд, и запросы идут через остальные
+func TestProxyPoolExcludesFailing(t *testing.T) {
+	urls, err := parseProxies([]string{"http://127.0.0.1:1", "socks5://127.0.0.1:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := newProxyPool(urls, rotationRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := pool.proxies[0]
+	for i := 0; i < 3; i++ {
+		pool.done(bad, errors.New("connection refused"))
+	}
+	for i := 0; i < 4; i++ {
+		if ps := pool.pick(); ps == bad {
+			t.Fatalf("выбран исключённый прокси %s", ps.url.Redacted())
+		}
+	}
+	if stats := pool.stats(); !stats[0].Down || stats[1].Down {
+		t.Errorf("исключён: %v и %v, ожидалось true и false", stats[0].Down, stats[1].Down)
+	}
+}
+
+// 502 от атакуемого сайта не считается ошибкой прокси, а 502,
+// сформированный самим прокси, и 407 — считаются
+func TestProxyBadGateway(t *testing.T) {
+	proxy := startHTTPProxy()
+	defer proxy.Close()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer flaky.Close()
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer auth.Close()
+
+	for _, c := range []struct {
+		name      string
+		proxy     string
+		target    string
+		wantCount bool
+	}{
+		{"502 от сайта", proxy.URL, flaky.URL, false},
+		{"502 от прокси: сайт недоступен", proxy.URL, "http://127.0.0.1:1/", true},
+		{"407 от прокси", auth.URL, flaky.URL, true},
+	} {
+		u, err := url.Parse(c.proxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool, err := newProxyPool([]*url.URL{u}, rotationRoundRobin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: newTransport(pool, false, 0)}
+		resp, err := client.Get(c.target)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if got := pool.stats()[0].Failures > 0; got != c.wantCount {
+			t.Errorf("%s: ошибка прокси учтена: %v, ожидалось %v (status code = %v)", c.name, got, c.wantCount, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+)
+
+// Простые прокси для сквозной проверки: HTTP-прокси с CONNECT и SOCKS5
+// без аутентификации. Счётчик requests показывает, что запросы шли через прокси.
+type stubProxy struct {
+	URL      string
+	requests atomic.Int64
+	close    func()
+}
+
+func (p *stubProxy) Close() { p.close() }
+
+// Передача данных в обе стороны до закрытия одного из соединений
+func tunnel(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// HTTP-прокси: запросы с абсолютным URL пересылаются, CONNECT открывает туннель
+func startHTTPProxy() *stubProxy {
+	p := new(stubProxy)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.requests.Add(1)
+		if r.Method == http.MethodConnect {
+			dst, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				dst.Close()
+				return
+			}
+			io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+			tunnel(conn, dst)
+			return
+		}
+
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+		out.Header.Del("Proxy-Connection")
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			// Ошибка самого прокси отмечается, как в RFC 9209
+			w.Header().Set("Proxy-Status", "stub; error=destination_unavailable")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	p.URL, p.close = srv.URL, srv.Close
+	return p
+}
+
+// SOCKS5-прокси (RFC 1928): только команда CONNECT без аутентификации
+func startSOCKS5Proxy() (*stubProxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &stubProxy{URL: "socks5://" + ln.Addr().String(), close: func() { ln.Close() }}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				p.requests.Add(1)
+				dst, err := socks5Handshake(conn)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				tunnel(conn, dst)
+			}()
+		}
+	}()
+	return p, nil
+}
+
+// Согласование SOCKS5 и соединение с запрошенным адресом
+func socks5Handshake(conn net.Conn) (net.Conn, error) {
+	r := bufio.NewReader(conn)
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	if head[0] != 5 {
+		return nil, errors.New("не SOCKS5")
+	}
+	if _, err := io.ReadFull(r, make([]byte, head[1])); err != nil {
+		return nil, err
+	}
+	conn.Write([]byte{5, 0}) // без аутентификации
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return nil, err
+	}
+	if req[1] != 1 {
+		return nil, fmt.Errorf("команда %d не поддерживается", req[1])
+	}
+	var host string
+	switch req[3] {
+	case 1, 4:
+		addr := make([]byte, map[byte]int{1: 4, 4: 16}[req[3]])
+		if _, err := io.ReadFull(r, addr); err != nil {
+			return nil, err
+		}
+		host = net.IP(addr).String()
+	case 3:
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	default:
+		return nil, fmt.Errorf("тип адреса %d не поддерживается", req[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return nil, err
+	}
+
+	dst, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0}) // соединение отклонено
+		return nil, err
+	}
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	return dst, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Ограничения нагрузки на атакуемое приложение. Нулевое значение поля
+// означает отсутствие ограничения.
+type limitConfig struct {
+	Rate       float64       // запросов в секунду ко всем хостам
+	HostRate   float64       // запросов в секунду к одному хосту
+	Jitter     time.Duration // случайная добавка к паузе перед запросом, от 0 до Jitter
+	MaxBackoff time.Duration // предел автоматического замедления и паузы по Retry-After
+	SlowFactor float64       // замедляться, если ответ дольше средней задержки в SlowFactor раз
+}
+
+// Очередь запросов с минимальным интервалом между ними
+type pacer struct {
+	interval time.Duration
+	next     time.Time // момент, начиная с которого можно отправить следующий запрос
+}
+
+// Резервирование времени отправки; extra увеличивает интервал после запроса
+func (p *pacer) reserve(now time.Time, extra time.Duration) time.Time {
+	if p.next.Before(now) {
+		p.next = now
+	}
+	at := p.next
+	p.next = p.next.Add(p.interval + extra)
+	return at
+}
+
+// Состояние одного хоста
+type hostState struct {
+	pacer
+	backoff time.Duration // текущее автоматическое замедление
+	raised  time.Time     // когда замедление увеличивалось в последний раз
+	until   time.Time     // до этого момента запросы не отправляются (Retry-After)
+	latency time.Duration // скользящая средняя задержка ответа
+	samples int
+}
+
+// Общий для всех попыток ограничитель нагрузки с подстройкой под ответы хостов:
+// на 429/503 и резкий рост задержки интервал удваивается, на обычные ответы
+// постепенно возвращается к заданному
+type limiter struct {
+	cfg    limitConfig
+	mu     sync.Mutex
+	global pacer
+	hosts  map[string]*hostState
+
+	slowdowns   int // сколько раз включалось замедление
+	retryAfters int // сколько раз приложение просило подождать
+}
+
+func newLimiter(cfg limitConfig) *limiter {
+	l := &limiter{cfg: cfg, hosts: make(map[string]*hostState)}
+	if cfg.Rate > 0 {
+		l.global.interval = time.Duration(float64(time.Second) / cfg.Rate)
+	}
+	return l
+}
+
+func (l *limiter) host(name string) *hostState {
+	h, ok := l.hosts[name]
+	if !ok {
+		h = new(hostState)
+		if l.cfg.HostRate > 0 {
+			h.interval = time.Duration(float64(time.Second) / l.cfg.HostRate)
+		}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// Ожидание перед запросом к хосту
+func (l *limiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	h := l.host(host)
+	if h.until.After(now) {
+		now = h.until
+	}
+	at := h.reserve(now, h.backoff)
+	if g := l.global.reserve(at, 0); g.After(at) {
+		at = g
+	}
+	l.mu.Unlock()
+
+	wait := time.Until(at)
+	if l.cfg.Jitter > 0 {
+		wait += rand.N(l.cfg.Jitter)
+	}
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	return sleepContext(ctx, wait)
+}
+
+// Учёт ответа хоста: код, заголовок Retry-After и задержка
+func (l *limiter) observe(host string, resp *http.Response, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.host(host)
+
+	overloaded := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+	if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok && overloaded {
+		if l.cfg.MaxBackoff > 0 && d > l.cfg.MaxBackoff {
+			d = l.cfg.MaxBackoff
+		}
+		if until := time.Now().Add(d); until.After(h.until) {
+			h.until = until
+			l.retryAfters++
+			fmt.Printf("%s просит подождать (Retry-After), пауза %s\n", host, d.Round(time.Millisecond))
+		}
+	}
+
+	// Средняя задержка считается по всем ответам, кроме 429/503, чтобы
+	// устойчивый рост задержки становился новой нормой, а сравнение с ней
+	// начинается после нескольких замеров. Ответы быстрее minSlow
+	// медленными не считаются, иначе на локальном стенде замедление
+	// включалось бы от любого колебания в доли миллисекунды.
+	const (
+		warmup  = 10
+		minSlow = 100 * time.Millisecond
+	)
+	slow := l.cfg.SlowFactor > 0 && h.samples >= warmup && latency > minSlow &&
+		float64(latency) > l.cfg.SlowFactor*float64(h.latency)
+	if !overloaded {
+		if h.samples == 0 {
+			h.latency = latency
+		} else {
+			h.latency += (latency - h.latency) / 8
+		}
+		h.samples++
+	}
+
+	// Пока действует прошлое увеличение, ответы на запросы, отправленные
+	// до него, замедление повторно не удваивают
+	now := time.Now()
+	switch {
+	case (overloaded || slow) && now.Sub(h.raised) < max(h.backoff, time.Second):
+	case overloaded || slow:
+		const minBackoff = 50 * time.Millisecond
+		backoff := max(2*h.backoff, minBackoff)
+		if l.cfg.MaxBackoff > 0 && backoff > l.cfg.MaxBackoff {
+			backoff = l.cfg.MaxBackoff
+		}
+		if backoff != h.backoff {
+			h.backoff = backoff
+			h.raised = now
+			l.slowdowns++
+			reason := fmt.Sprintf("status code = %v", resp.StatusCode)
+			if !overloaded {
+				reason = fmt.Sprintf("задержка %s при средней %s", latency.Round(time.Millisecond), h.latency.Round(time.Millisecond))
+			}
+			fmt.Printf("Замедление для %s: +%s между запросами (%s)\n", host, backoff.Round(time.Millisecond), reason)
+		}
+	case h.backoff > 0:
+		h.backoff -= h.backoff / 8
+		if h.backoff < time.Millisecond {
+			h.backoff = 0
+		}
+	}
+}
+
+// Значение Retry-After: число секунд или дата HTTP
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(t), 0), true
+}
+
+// Транспорт, пропускающий все запросы сессии через ограничитель
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *limiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.limiter.wait(req.Context(), host); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.limiter.observe(host, resp, time.Since(start))
+	return resp, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Ответ с кодом status и заголовком Retry-After, если он задан
+func limitResponse(status int, retry string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	if retry != "" {
+		resp.Header.Set("Retry-After", retry)
+	}
+	return resp
+}
+
+func TestPacerReserve(t *testing.T) {
+	t0 := time.Now()
+	p := pacer{interval: 100 * time.Millisecond}
+	for _, c := range []struct {
+		name  string
+		now   time.Time
+		extra time.Duration
+		want  time.Time
+	}{
+		{"первый запрос сразу", t0, 0, t0},
+		{"следующий через интервал", t0, 0, t0.Add(100 * time.Millisecond)},
+		{"с добавкой", t0, 50 * time.Millisecond, t0.Add(200 * time.Millisecond)},
+		{"добавка сдвигает следующий", t0, 0, t0.Add(350 * time.Millisecond)},
+		{"после простоя сразу", t0.Add(time.Second), 0, t0.Add(time.Second)},
+	} {
+		if got := p.reserve(c.now, c.extra); !got.Equal(c.want) {
+			t.Errorf("%s: через %s, ожидалось через %s", c.name, got.Sub(t0), c.want.Sub(t0))
+		}
+	}
+}
+
+// Общее ограничение действует на все хосты, ограничение хоста — только на него
+func TestLimiterWait(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		cfg     limitConfig
+		hosts   []string
+		minTime time.Duration
+	}{
+		{"общее ограничение на два хоста", limitConfig{Rate: 100}, []string{"a", "b", "a", "b", "a", "b"}, 50 * time.Millisecond},
+		{"ограничение одного хоста", limitConfig{HostRate: 100}, []string{"a", "a", "a", "a", "a", "a"}, 50 * time.Millisecond},
+		{"хосты с отдельными ограничениями", limitConfig{HostRate: 20}, []string{"a", "b", "c", "d"}, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			l := newLimiter(c.cfg)
+			start := time.Now()
+			for _, host := range c.hosts {
+				if err := l.wait(context.Background(), host); err != nil {
+					t.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < c.minTime {
+				t.Errorf("%d запросов за %s, ожидалось не быстрее %s", len(c.hosts), elapsed, c.minTime)
+			}
+			if c.minTime == 0 && elapsed > 100*time.Millisecond {
+				t.Errorf("запросы к разным хостам ждали друг друга: %s", elapsed)
+			}
+		})
+	}
+
+	l := newLimiter(limitConfig{HostRate: 1})
+	if err := l.wait(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, "a"); err == nil {
+		t.Error("ожидание не прервано отменой контекста")
+	}
+}
+
+// На 429 и 503 замедление удваивается не чаще раза за текущую паузу,
+// ограничено MaxBackoff и постепенно снимается обычными ответами
+func TestLimiterBackoff(t *testing.T) {
+	l := newLimiter(limitConfig{MaxBackoff: 150 * time.Millisecond})
+	h := l.host("a")
+	// Прошлое увеличение давнее: следующий ответ 429 снова удваивает паузу
+	expire := func() { h.raised = time.Now().Add(-time.Hour) }
+
+	for _, c := range []struct {
+		name   string
+		status int
+		before func()
+		want   time.Duration
+	}{
+		{"первый 429", http.StatusTooManyRequests, nil, 50 * time.Millisecond},
+		{"ответ на уже замедленный запрос", http.StatusTooManyRequests, nil, 50 * time.Millisecond},
+		{"503 после паузы", http.StatusServiceUnavailable, expire, 100 * time.Millisecond},
+		{"предел MaxBackoff", http.StatusTooManyRequests, expire, 150 * time.Millisecond},
+		{"на пределе не растёт", http.StatusTooManyRequests, expire, 150 * time.Millisecond},
+		{"обычный ответ снижает", http.StatusOK, nil, 150*time.Millisecond - 150*time.Millisecond/8},
+	} {
+		if c.before != nil {
+			c.before()
+		}
+		l.observe("a", limitResponse(c.status, ""), time.Millisecond)
+		if h.backoff != c.want {
+			t.Errorf("%s: замедление %s, ожидалось %s", c.name, h.backoff, c.want)
+		}
+	}
+	if l.slowdowns != 3 {
+		t.Errorf("замедление включалось %d раз, ожидалось 3", l.slowdowns)
+	}
+	for i := 0; i < 100 && h.backoff > 0; i++ {
+		l.observe("a", limitResponse(http.StatusOK, ""), time.Millisecond)
+	}
+	if h.backoff != 0 {
+		t.Errorf("после обычных ответов осталось замедление %s", h.backoff)
+	}
+	if b := l.host("b"); b.backoff != 0 {
+		t.Errorf("замедление другого хоста %s", b.backoff)
+	}
+}
+
+// Резкий рост задержки после нескольких замеров включает замедление,
+// а ответы быстрее 100 мс медленными не считаются
+func TestLimiterSlowResponses(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		normal  time.Duration
+		latency time.Duration
+		slow    bool
+	}{
+		{"рост задержки", 40 * time.Millisecond, 400 * time.Millisecond, true},
+		{"в пределах нормы", 40 * time.Millisecond, 110 * time.Millisecond, false},
+		{"быстрые ответы", time.Millisecond, 90 * time.Millisecond, false},
+	} {
+		l := newLimiter(limitConfig{SlowFactor: 3})
+		for i := 0; i < 10; i++ {
+			l.observe("a", limitResponse(http.StatusOK, ""), c.normal)
+		}
+		l.observe("a", limitResponse(http.StatusOK, ""), c.latency)
+		if got := l.host("a").backoff > 0; got != c.slow {
+			t.Errorf("%s: замедление %v, ожидалось %v", c.name, got, c.slow)
+		}
+	}
+}
+
+// Retry-After учитывается только в ответах 429 и 503 и ограничен MaxBackoff
+func TestLimiterRetryAfter(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		resp *http.Response
+		max  time.Duration
+		want time.Duration // 0 — пауза не назначается
+	}{
+		{"429 с секундами", limitResponse(http.StatusTooManyRequests, "2"), 0, 2 * time.Second},
+		{"503 с пределом", limitResponse(http.StatusServiceUnavailable, "60"), 300 * time.Millisecond, 300 * time.Millisecond},
+		{"200 с Retry-After", limitResponse(http.StatusOK, "60"), 0, 0},
+		{"429 без Retry-After", limitResponse(http.StatusTooManyRequests, ""), 0, 0},
+	} {
+		l := newLimiter(limitConfig{MaxBackoff: c.max})
+		before := time.Now()
+		l.observe("a", c.resp, time.Millisecond)
+		until := l.host("a").until
+		if c.want == 0 {
+			if !until.IsZero() || l.retryAfters != 0 {
+				t.Errorf("%s: назначена пауза до +%s", c.name, until.Sub(before))
+			}
+			continue
+		}
+		if d := until.Sub(before); d < c.want || d > c.want+time.Second {
+			t.Errorf("%s: пауза %s, ожидалось %s", c.name, d, c.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	future := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	for _, c := range []struct {
+		value    string
+		min, max time.Duration
+		ok       bool
+	}{
+		{"", 0, 0, false},
+		{"5", 5 * time.Second, 5 * time.Second, true},
+		{"-3", 0, 0, true},
+		{future, 80 * time.Second, 90 * time.Second, true},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, 0, true},
+		{"soon", 0, 0, false},
+		{strconv.Itoa(1 << 20), time.Duration(1<<20) * time.Second, time.Duration(1<<20) * time.Second, true},
+	} {
+		d, ok := retryAfter(c.value)
+		if ok != c.ok || d < c.min || d > c.max {
+			t.Errorf("%q: %s, %v; ожидалось от %s до %s, %v", c.value, d, ok, c.min, c.max, c.ok)
+		}
+	}
+}
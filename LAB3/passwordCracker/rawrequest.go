@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Имена параметров, значения которых заменяются метками подстановки.
+// Пустое имя — параметр не размечается.
+type injectionParams struct {
+	user, pass, token string
+}
+
+func (p injectionParams) pairs() [][2]string {
+	return [][2]string{
+		{p.user, placeholderUser},
+		{p.pass, placeholderPass},
+		{p.token, placeholderToken},
+	}
+}
+
+// Разметка параметров в строке вида a=1&b=2 (строка запроса или тело формы)
+// без перекодирования остальных параметров
+func markFormParams(raw string, params injectionParams) string {
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		key, _, _ := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		for _, p := range params.pairs() {
+			if p[0] != "" && name == p[0] {
+				parts[i] = key + "=" + p[1]
+			}
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Разметка строковых полей JSON-тела
+func markJSONParams(body string, params injectionParams) string {
+	for _, p := range params.pairs() {
+		if p[0] == "" {
+			continue
+		}
+		re := regexp.MustCompile(`("` + regexp.QuoteMeta(p[0]) + `"\s*:\s*")(?:[^"\\]|\\.)*(")`)
+		body = re.ReplaceAllString(body, "${1}"+p[1]+"${2}")
+	}
+	return body
+}
+
+// Заголовки, которые не переносятся в шаблон: длина тела меняется
+// при подстановке, а сжатие ответа обрабатывает транспорт net/http
+var skippedRawHeaders = []string{"Content-Length", "Accept-Encoding", "Cookie"}
+
+// Разделение файла по первой пустой строке на заголовок запроса и тело.
+// Переводы строк \r\n приводятся к \n только в заголовке: тело, например
+// multipart или двоичное, остаётся байт в байт.
+func splitRawRequest(data []byte) (head, body []byte) {
+	rest := data
+	for {
+		line, after, found := bytes.Cut(rest, []byte("\n"))
+		if len(bytes.TrimSuffix(line, []byte("\r"))) == 0 && found {
+			head = data[:len(data)-len(rest)]
+			body = after
+			break
+		}
+		if !found {
+			head = data
+			break
+		}
+		rest = after
+	}
+	return bytes.ReplaceAll(head, []byte("\r\n"), []byte("\n")), body
+}
+
+// Загрузка сырого HTTP/1.1 запроса, сохранённого из перехватывающего прокси,
+// как шаблона атаки. Места подстановки отмечаются в файле метками §USER§,
+// §PASS§, §TOKEN§ или задаются именами параметров в params. Все заголовки
+// сохраняются, а значение Cookie возвращается отдельно, чтобы засеять им
+// хранилище cookie сессии. Тело читается до конца файла без учёта Content-Length;
+// у тел формы и JSON отбрасываются завершающие переводы строк, которые
+// добавляют редакторы и прокси. Без меток §USER§ и §PASS§ каждая попытка
+// повторяла бы перехваченные учётные данные, поэтому это ошибка.
+func loadRawRequest(path, scheme string, params injectionParams) (*requestTemplate, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	head, body := splitRawRequest(data)
+	if !bytes.HasSuffix(head, []byte("\n")) {
+		head = append(head, '\n')
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(append(head, '\n'))))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if req.Host == "" {
+		return nil, "", fmt.Errorf("%s: в запросе нет заголовка Host", path)
+	}
+
+	t := &requestTemplate{
+		Method:  req.Method,
+		Headers: make(map[string]string),
+		Body:    string(body),
+	}
+	uri, query, hasQuery := strings.Cut(req.RequestURI, "?")
+	if hasQuery {
+		uri += "?" + markFormParams(query, params)
+	}
+	t.URL = scheme + "://" + req.Host + uri
+
+	cookie := strings.Join(req.Header.Values("Cookie"), "; ")
+	for _, h := range skippedRawHeaders {
+		req.Header.Del(h)
+	}
+	for k, v := range req.Header {
+		t.Headers[k] = strings.Join(v, ", ")
+	}
+
+	switch ct := t.contentType(); {
+	case strings.Contains(ct, "json"):
+		t.Body = markJSONParams(strings.TrimRight(t.Body, "\r\n"), params)
+	case strings.Contains(ct, "x-www-form-urlencoded"):
+		t.Body = markFormParams(strings.TrimRight(t.Body, "\r\n"), params)
+	}
+
+	for _, p := range []struct{ placeholder, flag string }{
+		{placeholderUser, "-user-param"},
+		{placeholderPass, "-pass-param"},
+	} {
+		if !t.uses(p.placeholder) {
+			return nil, "", fmt.Errorf("%s: в запросе нет метки %s; отметьте место подстановки в файле или укажите параметр флагом %s", path, p.placeholder, p.flag)
+		}
+	}
+	return t, cookie, nil
+}
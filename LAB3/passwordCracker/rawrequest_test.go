@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Cookie из сырого запроса отправляются на хост запроса, даже если -url
+// указывает на другой хост
+func TestRawRequestCookiesReachRequestHost(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Cookie"))
+		w.Write([]byte("Username and/or password incorrect."))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	raw := "POST /app/login HTTP/1.1\r\nHost: " + u.Host + "\r\n" +
+		"Cookie: PHPSESSID=abc123; security=low\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\n\r\n" +
+		"username=admin&password=x&Login=Login"
+	path := filepath.Join(t.TempDir(), "login.req")
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, cookie, err := loadRawRequest(path, "http", injectionParams{user: "username", pass: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name       string
+		flagURL    string // значение -url, пусто — флаг не задан
+		wantTarget string
+	}{
+		{"адрес из запроса", "", srv.URL + "/app/login"},
+		{"-url на другом хосте", "http://localhost/dvwa/vulnerabilities/brute/", "http://localhost/dvwa/vulnerabilities/brute/"},
+	} {
+		got = nil
+		// Как в main: без -url адрес берётся из запроса
+		target, _ := url.Parse("http://localhost/dvwa/vulnerabilities/brute/")
+		if c.flagURL != "" {
+			target, _ = url.Parse(c.flagURL)
+		} else if u := tmpl.target(target); u != nil {
+			target = u
+		}
+		if target.String() != c.wantTarget {
+			t.Errorf("%s: адрес %s, ожидался %s", c.name, target, c.wantTarget)
+		}
+		sess, err := newSession(sessionConfig{Target: target, Cookie: cookie, Template: tmpl})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := sess.attempt(context.Background(), "admin", "secret")
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if len(got) != 1 || !strings.Contains(got[0], "PHPSESSID=abc123") || !strings.Contains(got[0], "security=low") {
+			t.Errorf("%s: сервер получил cookie %q", c.name, got)
+		}
+	}
+}
+
+func TestMarkFormParams(t *testing.T) {
+	params := injectionParams{user: "username", pass: "password", token: "user_token"}
+	for _, c := range []struct {
+		name, raw, want string
+	}{
+		{"все параметры", "username=admin&password=x&user_token=abc&Login=Login",
+			"username=§USER§&password=§PASS§&user_token=§TOKEN§&Login=Login"},
+		{"остальные параметры не перекодируются", "a=%2F%20&username=admin&b=x+y",
+			"a=%2F%20&username=§USER§&b=x+y"},
+		{"закодированное имя", "user%6Eame=admin&pass%77ord=x", "user%6Eame=§USER§&pass%77ord=§PASS§"},
+		{"значение с равно и пустое значение", "username=a=b&password=", "username=§USER§&password=§PASS§"},
+		{"параметр без значения", "username&password", "username=§USER§&password=§PASS§"},
+		{"похожие имена не размечаются", "username2=admin&my_password=x", "username2=admin&my_password=x"},
+		{"испорченное имя пропускается", "%zz=1&username=admin", "%zz=1&username=§USER§"},
+	} {
+		if got := markFormParams(c.raw, params); got != c.want {
+			t.Errorf("%s: %q, ожидалось %q", c.name, got, c.want)
+		}
+	}
+	if got := markFormParams("username=admin", injectionParams{}); got != "username=admin" {
+		t.Errorf("без имён параметров: %q", got)
+	}
+}
+
+func TestMarkJSONParams(t *testing.T) {
+	params := injectionParams{user: "login", pass: "pass"}
+	for _, c := range []struct {
+		name, body, want string
+	}{
+		{"строковые поля", `{"login": "admin", "pass": "x"}`, `{"login": "§USER§", "pass": "§PASS§"}`},
+		{"без пробелов и с экранированием", `{"login":"a\"b","pass":"c\\"}`, `{"login":"§USER§","pass":"§PASS§"}`},
+		{"вложенный объект", `{"data": {"login": "admin"}, "pass": "x"}`, `{"data": {"login": "§USER§"}, "pass": "§PASS§"}`},
+		{"нестроковое значение не размечается", `{"login": 1, "pass": null}`, `{"login": 1, "pass": null}`},
+		{"ключ в значении не размечается", `{"note": "login", "other": "x"}`, `{"note": "login", "other": "x"}`},
+	} {
+		if got := markJSONParams(c.body, params); got != c.want {
+			t.Errorf("%s: %s, ожидалось %s", c.name, got, c.want)
+		}
+	}
+}
+
+// Разбор сырого запроса: ошибки в заголовке, метки подстановки
+// и тело без изменений
+func TestLoadRawRequest(t *testing.T) {
+	multipart := "--b\r\nContent-Disposition: form-data; name=\"username\"\r\n\r\n§USER§\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\n§PASS§\r\n--b--\r\n"
+	params := injectionParams{user: "username", pass: "password"}
+	for _, c := range []struct {
+		name     string
+		raw      string
+		wantURL  string
+		wantBody string
+		wantErr  string // подстрока ошибки, пусто — ошибки нет
+	}{
+		{"форма с CRLF", "POST /login HTTP/1.1\r\nHost: app\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\nusername=a&password=b\r\n",
+			"http://app/login", "username=§USER§&password=§PASS§", ""},
+		{"только LF и строка запроса без тела", "GET /login?username=a&password=b HTTP/1.1\nHost: app",
+			"http://app/login?username=§USER§&password=§PASS§", "", ""},
+		{"multipart сохраняется байт в байт", "POST /upload HTTP/1.1\r\nHost: app\r\nContent-Type: multipart/form-data; boundary=b\r\n\r\n" + multipart,
+			"http://app/upload", multipart, ""},
+		{"двоичное тело с CRLF", "POST /login?username=a&password=b HTTP/1.1\nHost: app\nContent-Type: application/octet-stream\n\n\x00\r\n\x01\r\n\r\n",
+			"http://app/login?username=§USER§&password=§PASS§", "\x00\r\n\x01\r\n\r\n", ""},
+		{"нет строки запроса", "Host: app\r\n\r\n", "", "", "malformed HTTP"},
+		{"неизвестная версия HTTP", "GET / HTTP/9.x\r\nHost: app\r\n\r\n", "", "", "malformed HTTP version"},
+		{"испорченный заголовок", "GET / HTTP/1.1\r\nHost app\r\n\r\n", "", "", "malformed MIME header"},
+		{"нет Host", "GET /?username=a&password=b HTTP/1.1\r\nAccept: */*\r\n\r\n", "", "", "нет заголовка Host"},
+		{"пустой файл", "", "", "", "malformed HTTP request"},
+		{"нет метки пароля", "GET /login?username=a&pwd=b HTTP/1.1\r\nHost: app\r\n\r\n", "", "", "нет метки §PASS§"},
+		{"нет меток", "POST /login HTTP/1.1\r\nHost: app\r\nContent-Type: text/plain\r\n\r\nusername=a&password=b", "", "", "нет метки §USER§"},
+	} {
+		path := filepath.Join(t.TempDir(), "login.req")
+		if err := os.WriteFile(path, []byte(c.raw), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		tmpl, _, err := loadRawRequest(path, "http", params)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: ошибка %v, ожидалась с %q", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if tmpl.URL != c.wantURL || tmpl.Body != c.wantBody {
+			t.Errorf("%s: %s %q, ожидалось %s %q", c.name, tmpl.URL, tmpl.Body, c.wantURL, c.wantBody)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"auth/server"
+)
+
+// Пороги, в которых защита LAB3/authSystem должна остановить атаку
+const (
+	replayAttempts = 10 // неудачных попыток одного логина, после которых он блокируется
+	replayRequests = 30 // попыток распыления, после которых срабатывает ограничение частоты
+)
+
+// Учётные записи системы на время проверки
+var replayUsers = map[string]string{
+	"admin":   "password",
+	"gordonb": "abc123",
+	"pablo":   "letmein",
+}
+
+// Итог атаки на систему
+type replayOutcome struct {
+	attempts int    // выполнено попыток
+	stopped  int    // номер попытки, на которой сработала защита, 0 — не сработала
+	defense  string // сработавшая защита
+	found    map[string]string
+}
+
+// Атака по стратегии с токеном token в одном потоке, чтобы попытки шли
+// по порядку. Атака прекращается, когда срабатывает защита, найден пароль
+// или попытка закончилась ошибкой.
+func replayAttack(ctx context.Context, srvURL, strategy string, lists *attackLists, token string) (replayOutcome, error) {
+	var out replayOutcome
+	u, err := url.Parse(srvURL + "/login")
+	if err != nil {
+		return out, err
+	}
+	auth, err := newAuthenticator(protocolAuthSys, protocolConfig{Target: u, AuthToken: token})
+	if err != nil {
+		return out, err
+	}
+	cls, err := newClassifier(protocolRules(protocolAuthSys, ""))
+	if err != nil {
+		return out, err
+	}
+	out.found, err = collectFound(ctx, 1, strategy, lists, loginAttempt(auth, cls), func(r result) bool {
+		out.attempts++
+		if r.defense != "" {
+			out.stopped, out.defense = out.attempts, r.defense
+		}
+		return r.defense != "" || r.verdict == verdictSuccess || r.verdict == verdictError
+	})
+	return out, err
+}
+
+// Попытка входа в обход перебора: код и длина ответа
+func replayLogin(srvURL, login, pass, token string) (int, int, error) {
+	resp, err := http.PostForm(srvURL+"/login", url.Values{"username": {login}, "password": {pass}, "token": {token}})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, len(body), err
+}
+
+// Код ответа /check-token на токен token
+func replayCheckToken(srvURL, token string) (int, error) {
+	resp, err := http.PostForm(srvURL+"/check-token", url.Values{"token": {token}})
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Заведомо неверные пароли
+func replayPasswords(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = "wrong" + strconv.Itoa(i+1)
+	}
+	return words
+}
+
+// Вход владельца учётной записи: верные пароль и токен принимаются,
+// иначе остальные проверки ничего не доказывают
+func replayOwner(srvURL, token string) error {
+	status, _, err := replayLogin(srvURL, "admin", replayUsers["admin"], token)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("верные пароль и токен отвергнуты (status code = %v)", status)
+	}
+	if status, err = replayCheckToken(srvURL, token); err == nil && status != http.StatusOK {
+		err = fmt.Errorf("/check-token не принимает выданный токен (status code = %v)", status)
+	}
+	return err
+}
+
+// Словарная атака на admin, которой известен токен, например перехваченный.
+// Верный пароль стоит в словаре после 2*replayAttempts неверных; блокировка
+// должна сработать не позже попытки replayAttempts+1 и не сниматься верным паролем.
+func replayDictionary(ctx context.Context, srvURL, token string) error {
+	lists := &attackLists{
+		users:     []string{"admin"},
+		passwords: append(replayPasswords(2*replayAttempts), replayUsers["admin"]),
+	}
+	out, err := replayAttack(ctx, srvURL, strategyClusterBomb, lists, token)
+	switch {
+	case err != nil:
+		return err
+	case len(out.found) > 0:
+		return fmt.Errorf("пароль найден на попытке %d: %v", out.attempts, out.found)
+	case out.stopped == 0:
+		return fmt.Errorf("защита не сработала за %d попыток", out.attempts)
+	case out.stopped > replayAttempts+1:
+		return fmt.Errorf("%s сработала на попытке %d, порог %d", out.defense, out.stopped, replayAttempts+1)
+	}
+	status, _, err := replayLogin(srvURL, "admin", replayUsers["admin"], token)
+	if err == nil && status == http.StatusOK {
+		err = errors.New("после блокировки вход с верными паролем и токеном выполнен")
+	}
+	return err
+}
+
+// Распыление без токена по всем логинам и несуществующим, чтобы блокировка
+// отдельных логинов не наступила раньше ограничения частоты
+func replaySpray(ctx context.Context, srvURL string) error {
+	users := slices.Sorted(maps.Keys(replayUsers))
+	for i := 1; i <= 5; i++ {
+		users = append(users, "user"+strconv.Itoa(i))
+	}
+	lists := &attackLists{
+		users:     users,
+		passwords: replayPasswords((2*replayRequests + len(users) - 1) / len(users)),
+	}
+	out, err := replayAttack(ctx, srvURL, strategySpray, lists, "")
+	switch {
+	case err != nil:
+		return err
+	case len(out.found) > 0:
+		return fmt.Errorf("найдено без токена: %v", out.found)
+	case out.defense != defenseRateLimit:
+		return fmt.Errorf("ограничение частоты не сработало за %d попыток", out.attempts)
+	case out.stopped > replayRequests+1:
+		return fmt.Errorf("ограничение частоты сработало на попытке %d, порог %d", out.stopped, replayRequests+1)
+	}
+	return nil
+}
+
+// Повтор чужого, пустого и подделанного токена с верным паролем admin.
+// Вход не должен выполняться, а ответ не должен отличаться от ответа
+// на неверный пароль: иначе по нему подбирается пароль без токена.
+// Подделка повторяет строку, из которой authSystem получает токен.
+func replayTokens(srvURL, token, otherToken string) error {
+	forged := fmt.Sprintf("admin-%d", time.Now().Unix())
+	for _, c := range []struct {
+		name  string
+		token string
+	}{
+		{"пустой токен", ""},
+		{"токен другого пользователя", otherToken},
+		{"подделанный токен", forged},
+	} {
+		status, length, err := replayLogin(srvURL, "admin", replayUsers["admin"], c.token)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK {
+			return fmt.Errorf("%s: вход выполнен", c.name)
+		}
+		wrongStatus, wrongLength, err := replayLogin(srvURL, "admin", "wrong", token)
+		if err != nil {
+			return err
+		}
+		if status != wrongStatus || length != wrongLength {
+			return fmt.Errorf("%s: ответ на верный пароль (status code = %v, длина %d) отличается от ответа на неверный (status code = %v, длина %d)",
+				c.name, status, length, wrongStatus, wrongLength)
+		}
+	}
+	status, err := replayCheckToken(srvURL, forged)
+	if err == nil && status == http.StatusOK {
+		err = errors.New("/check-token принимает подделанный токен")
+	}
+	return err
+}
+
+// Атаки переборщика на LAB3/authSystem, запущенную в этом же процессе:
+// словарная атака, распыление и повтор токенов. Для каждой проверки
+// система создаётся заново, чтобы блокировки одной не влияли на другие.
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		name string
+		run  func(srvURL string, srv *server.Server) error
+	}{
+		{"вход владельца", func(srvURL string, srv *server.Server) error {
+			return replayOwner(srvURL, srv.Token("admin"))
+		}},
+		{fmt.Sprintf("словарная атака: блокировка за %d попыток", replayAttempts), func(srvURL string, srv *server.Server) error {
+			return replayDictionary(ctx, srvURL, srv.Token("admin"))
+		}},
+		{fmt.Sprintf("распыление: ограничение частоты за %d попыток", replayRequests), func(srvURL string, _ *server.Server) error {
+			return replaySpray(ctx, srvURL)
+		}},
+		{"повтор токенов", func(srvURL string, srv *server.Server) error {
+			return replayTokens(srvURL, srv.Token("admin"), srv.Token("gordonb"))
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			srv := server.New(replayUsers)
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			if err := c.run(ts.URL, srv); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
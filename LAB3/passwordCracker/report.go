@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Итоговый отчёт об атаке для приложения к результатам тестирования
+type attackReport struct {
+	Target    string            `json:"target"`
+	Strategy  string            `json:"strategy"`
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Complete  bool              `json:"complete"` // все пары проверены, атака не прервана
+	Attempts  int               `json:"attempts"`
+	Failures  int               `json:"failures"`
+	Errors    int               `json:"errors"`
+	Lockouts  int               `json:"lockouts"`
+	Cancelled int               `json:"cancelled"`
+	Skipped   int               `json:"skipped"`
+	Found     []foundCredential `json:"found"`
+	Defenses  []defenseSummary  `json:"defenses"`
+	Timing    timingSummary     `json:"timing"`
+	Proxies   []proxySummary    `json:"proxies,omitempty"`
+}
+
+type foundCredential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Распределение времени ответа на попытки
+type timingSummary struct {
+	Samples   int               `json:"samples"`
+	MinMS     float64           `json:"min_ms"`
+	MedianMS  float64           `json:"median_ms"`
+	P95MS     float64           `json:"p95_ms"`
+	MaxMS     float64           `json:"max_ms"`
+	Histogram []histogramBucket `json:"histogram"`
+}
+
+// Интервал гистограммы [FromMS, ToMS)
+type histogramBucket struct {
+	FromMS float64 `json:"from_ms"`
+	ToMS   float64 `json:"to_ms"`
+	Count  int     `json:"count"`
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Сводка по времени ответа. Интервалы гистограммы удваиваются начиная
+// с 1 мс: время ответа обычно различается на порядки, и в равных
+// интервалах быстрые ответы сливаются в один столбец.
+func timingHistogram(latencies []time.Duration) timingSummary {
+	if len(latencies) == 0 {
+		return timingSummary{}
+	}
+	s := slices.Clone(latencies)
+	slices.Sort(s)
+	t := timingSummary{
+		Samples:  len(s),
+		MinMS:    ms(s[0]),
+		MedianMS: ms(s[len(s)/2]),
+		P95MS:    ms(s[(len(s)*95-1)/100]),
+		MaxMS:    ms(s[len(s)-1]),
+	}
+	counts := make(map[int]int)
+	lo, hi := -1, -1
+	for _, d := range s {
+		b := 0
+		for edge := time.Millisecond; d >= edge; edge *= 2 {
+			b++
+		}
+		counts[b]++
+		if lo < 0 {
+			lo = b
+		}
+		hi = b
+	}
+	for b := lo; b <= hi; b++ {
+		from := 0.0
+		if b > 0 {
+			from = float64(int64(1) << (b - 1))
+		}
+		t.Histogram = append(t.Histogram, histogramBucket{FromMS: from, ToMS: float64(int64(1) << b), Count: counts[b]})
+	}
+	return t
+}
+
+// Запись отчёта в <base>.json и <base>.md; расширение в path отбрасывается.
+// Возвращает base.
+func (rep *attackReport) write(path string) (string, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return base, os.WriteFile(base+".md", []byte(rep.markdown()), 0o644)
+}
+
+func (rep *attackReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Отчёт об атаке на %s\n\n", rep.Target)
+	state := "завершена"
+	if !rep.Complete {
+		state = "прервана, проверены не все пары"
+	}
+	fmt.Fprintf(&b, "- Стратегия: %s\n", rep.Strategy)
+	fmt.Fprintf(&b, "- Начало: %s\n", rep.Started.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Длительность: %s\n", rep.Finished.Sub(rep.Started).Round(time.Second))
+	fmt.Fprintf(&b, "- Атака %s\n\n", state)
+
+	b.WriteString("## Итоги\n\n| Показатель | Значение |\n|---|---:|\n")
+	for _, row := range []struct {
+		name  string
+		value int
+	}{
+		{"Попыток", rep.Attempts},
+		{"Неудачных входов", rep.Failures},
+		{"Ошибок", rep.Errors},
+		{"Блокировок", rep.Lockouts},
+		{"Отменено", rep.Cancelled},
+		{"Пропущено", rep.Skipped},
+		{"Найдено учётных данных", len(rep.Found)},
+	} {
+		fmt.Fprintf(&b, "| %s | %d |\n", row.name, row.value)
+	}
+
+	b.WriteString("\n## Найденные учётные данные\n\n")
+	if len(rep.Found) == 0 {
+		b.WriteString("Не найдены.\n")
+	} else {
+		b.WriteString("| Логин | Пароль |\n|---|---|\n")
+		for _, f := range rep.Found {
+			fmt.Fprintf(&b, "| %s | %s |\n", mdEscape(f.User), mdEscape(f.Password))
+		}
+	}
+
+	b.WriteString("\n## Обнаруженные средства защиты\n\n")
+	if len(rep.Defenses) == 0 {
+		b.WriteString("Не обнаружены.\n")
+	} else {
+		b.WriteString("| Защита | Срабатываний | Логинов | Впервые | Пример ответа |\n|---|---:|---:|---|---|\n")
+		for _, d := range rep.Defenses {
+			fmt.Fprintf(&b, "| %s | %d | %d | %s | %s |\n",
+				d.Kind, d.Count, d.Users, d.First.Format("15:04:05"), mdEscape(d.Proof))
+		}
+	}
+
+	b.WriteString("\n## Время ответа\n\n")
+	t := rep.Timing
+	if t.Samples == 0 {
+		b.WriteString("Нет замеров.\n")
+	} else {
+		fmt.Fprintf(&b, "Замеров: %d, минимум %.1f мс, медиана %.1f мс, 95%% — %.1f мс, максимум %.1f мс.\n\n",
+			t.Samples, t.MinMS, t.MedianMS, t.P95MS, t.MaxMS)
+		b.WriteString("| Интервал, мс | Ответов | |\n|---|---:|---|\n")
+		most := 0
+		for _, h := range t.Histogram {
+			most = max(most, h.Count)
+		}
+		for _, h := range t.Histogram {
+			bar := strings.Repeat("█", (h.Count*40+most-1)/most)
+			fmt.Fprintf(&b, "| %g–%g | %d | %s |\n", h.FromMS, h.ToMS, h.Count, bar)
+		}
+	}
+
+	if len(rep.Proxies) > 0 {
+		b.WriteString("\n## Прокси\n\n| Прокси | Запросов | Ошибок | Исключён |\n|---|---:|---:|---|\n")
+		for _, p := range rep.Proxies {
+			down := "нет"
+			if p.Down {
+				down = "да"
+			}
+			fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", mdEscape(p.URL), p.Requests, p.Failures, down)
+		}
+	}
+	return b.String()
+}
+
+// Экранирование текста для ячейки таблицы Markdown
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ", "`", "\\`").Replace(s)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Найденные учётные данные, общие для всех горутин пула. Когда пароль
+// логина найден, отменяются его оставшиеся и уже начатые попытки.
+type foundSet struct {
+	mu    sync.Mutex
+	found map[string]string // логин -> пароль
+	order []string          // логины в порядке нахождения
+	users map[string]userCancel
+	stop  context.CancelFunc // остановка всей атаки после первого найденного, nil — не останавливать
+}
+
+// Контекст попыток одного логина
+type userCancel struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// initial — пароли, найденные в прошлом запуске
+func newFoundSet(initial map[string]string, stop context.CancelFunc) *foundSet {
+	s := &foundSet{
+		found: make(map[string]string),
+		users: make(map[string]userCancel),
+		stop:  stop,
+	}
+	for login, pass := range initial {
+		s.found[login] = pass
+		s.order = append(s.order, login)
+	}
+	sort.Strings(s.order)
+	return s
+}
+
+// Добавление найденной пары. Возвращает false, если пароль логина уже известен.
+func (s *foundSet) add(j job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.found[j.login]; ok {
+		return false
+	}
+	s.found[j.login] = j.pass
+	s.order = append(s.order, j.login)
+	if u, ok := s.users[j.login]; ok {
+		u.cancel()
+	}
+	if s.stop != nil {
+		s.stop()
+	}
+	return true
+}
+
+// Контекст попыток логина: отменяется, когда пароль найден
+func (s *foundSet) userContext(parent context.Context, login string) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[login]
+	if !ok {
+		u.ctx, u.cancel = context.WithCancel(parent)
+		if _, found := s.found[login]; found {
+			u.cancel()
+		}
+		s.users[login] = u
+	}
+	return u.ctx
+}
+
+// Освобождение контекстов логинов после завершения атаки
+func (s *foundSet) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		u.cancel()
+	}
+}
+
+// Найденные пары в порядке нахождения
+func (s *foundSet) all() []job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hits := make([]job, len(s.order))
+	for i, login := range s.order {
+		hits[i] = job{login: login, pass: s.found[login]}
+	}
+	return hits
+}
+
+// Попытка с учётом найденных паролей: для уже взломанного логина попытка
+// не выполняется, а прерванная отменой попытка помечается как пропущенная,
+// чтобы не попасть в ошибки и при продолжении атаки выполниться снова
+func (s *foundSet) guard(attempt func(context.Context, job) result) func(context.Context, job) result {
+	return func(ctx context.Context, j job) result {
+		uctx := s.userContext(ctx, j.login)
+		if uctx.Err() != nil {
+			return result{job: j, verdict: verdictSkipped}
+		}
+		r := attempt(uctx, j)
+		switch {
+		case r.verdict == verdictSuccess:
+			if !s.add(j) {
+				// Пароль уже найден параллельной попыткой с тем же логином
+				r.verdict = verdictSkipped
+			}
+		case uctx.Err() != nil && errors.Is(r.err, context.Canceled):
+			r.verdict = verdictSkipped
+		}
+		return r
+	}
+}
+
+// Итоговый список найденных учётных данных
+func printFound(hits []job) {
+	if len(hits) == 0 {
+		fmt.Printf("Пароль не найден!\n")
+		return
+	}
+	fmt.Printf("Найдено учётных данных: %d\n", len(hits))
+	for _, h := range hits {
+		fmt.Printf("  login: %v, pass: %v\n", h.login, h.pass)
+	}
+}
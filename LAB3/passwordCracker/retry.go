@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Классы ошибок попытки входа
+const (
+	errClassTimeout   = "таймаут"
+	errClassConn      = "обрыв соединения"
+	errClassServer    = "ошибка сервера"
+	errClassCancelled = "отменено"
+	errClassOther     = "прочее"
+)
+
+// Класс ошибки попытки и можно ли её повторить. Повторяются таймауты,
+// обрывы и отказы соединения и ответы 5xx; остальные ошибки
+// (непонятный ответ, 4xx, ошибки шаблона) при повторе не исчезнут.
+func classifyError(r result) (string, bool) {
+	var netErr net.Error
+	switch {
+	case errors.Is(r.err, context.Canceled):
+		return errClassCancelled, false
+	case errors.Is(r.err, context.DeadlineExceeded), errors.As(r.err, &netErr) && netErr.Timeout():
+		return errClassTimeout, true
+	case errors.Is(r.err, syscall.ECONNRESET), errors.Is(r.err, syscall.ECONNREFUSED),
+		errors.Is(r.err, syscall.ECONNABORTED), errors.Is(r.err, syscall.EPIPE),
+		errors.Is(r.err, io.EOF), errors.Is(r.err, io.ErrUnexpectedEOF):
+		return errClassConn, true
+	case r.status >= 500:
+		return errClassServer, true
+	}
+	return errClassOther, false
+}
+
+// Политика повторов
+type retryPolicy struct {
+	Retries  int           // повторов после первой попытки
+	Delay    time.Duration // пауза перед первым повтором, дальше удваивается
+	MaxDelay time.Duration // предел паузы
+}
+
+// Пауза перед повтором номер n (с нуля) со случайной добавкой до половины паузы,
+// чтобы повторы разных потоков не приходили одновременно. При нулевой
+// паузе повторы идут сразу.
+func (p retryPolicy) backoff(n int) time.Duration {
+	if p.Delay <= 0 {
+		return 0
+	}
+	shift := min(n, 30)
+	d := p.Delay << shift
+	overflow := d>>shift != p.Delay
+	switch {
+	case p.MaxDelay > 0 && (overflow || d > p.MaxDelay):
+		d = p.MaxDelay
+	case overflow:
+		d = math.MaxInt64 / 2
+	}
+	if d > 1 {
+		d += rand.N(d / 2)
+	}
+	return d
+}
+
+// Попытка с повторами при временных ошибках
+func (p retryPolicy) wrap(attempt func(context.Context, job) result) func(context.Context, job) result {
+	return func(ctx context.Context, j job) result {
+		for n := 0; ; n++ {
+			r := attempt(ctx, j)
+			r.tries = n + 1
+			if r.verdict != verdictError {
+				return r
+			}
+			class, retryable := classifyError(r)
+			if !retryable || n >= p.Retries || ctx.Err() != nil {
+				return r
+			}
+			d := p.backoff(n)
+			fmt.Printf("%v / %v: %s (%v), повтор через %s\n", j.login, j.pass, class, r.err, d.Round(time.Millisecond))
+			if sleepContext(ctx, d) != nil {
+				return r
+			}
+		}
+	}
+}
+
+// Запись пар, которые не удалось проверить, в формате логин:пароль:
+// файл подходит для повторного запуска с -strategy stuffing -combo.
+// Если таких пар нет, файл от прошлого запуска удаляется.
+func writeFailedQueue(path string, failed []job) error {
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	lines := make([]string, len(failed))
+	for i, j := range failed {
+		lines[i] = j.login + ":" + j.pass
+	}
+	return writeLines(path, lines)
+}
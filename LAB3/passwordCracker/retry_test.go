@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		policy retryPolicy
+		n      int
+		min    time.Duration // пауза без случайной добавки
+	}{
+		{"без паузы", retryPolicy{Delay: 0, MaxDelay: 30 * time.Second}, 3, 0},
+		{"первый повтор", retryPolicy{Delay: time.Second, MaxDelay: 30 * time.Second}, 0, time.Second},
+		{"удвоение", retryPolicy{Delay: time.Second, MaxDelay: 30 * time.Second}, 2, 4 * time.Second},
+		{"предел", retryPolicy{Delay: time.Second, MaxDelay: 30 * time.Second}, 10, 30 * time.Second},
+		{"переполнение сдвига", retryPolicy{Delay: time.Hour, MaxDelay: 30 * time.Second}, 100, 30 * time.Second},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			d := c.policy.backoff(c.n)
+			if d < c.min || d > c.min+c.min/2 {
+				t.Errorf("backoff(%d) = %s, ожидалось от %s до %s", c.n, d, c.min, c.min+c.min/2)
+			}
+		})
+	}
+}
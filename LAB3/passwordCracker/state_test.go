@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Состояние после первого запуска сохраняется в файл и загружается при
+// продолжении: проверенные пары и все пары логина с найденным паролем
+// пропускаются, а заблокированные и ошибочные пробуются снова
+func TestAttackStateResume(t *testing.T) {
+	pairs := []job{
+		{"admin", "123456"}, {"admin", "password"}, {"admin", "qwerty"},
+		{"gordonb", "123456"}, {"gordonb", "abc123"},
+		{"pablo", "123456"}, {"pablo", "letmein"},
+	}
+	r := func(login, pass string, v verdict) result {
+		return result{job: job{login: login, pass: pass}, verdict: v}
+	}
+	for _, c := range []struct {
+		name     string
+		recorded []result
+		want     []job // пары, которые будут пробоваться при продолжении
+	}{
+		{"новая атака", nil, pairs},
+		{"проверенные пары", []result{
+			r("admin", "123456", verdictFailure),
+			r("gordonb", "123456", verdictFailure),
+		}, []job{{"admin", "password"}, {"admin", "qwerty"}, {"gordonb", "abc123"}, {"pablo", "123456"}, {"pablo", "letmein"}}},
+		{"найденный пароль исключает логин", []result{
+			r("admin", "password", verdictSuccess),
+			r("pablo", "123456", verdictFailure),
+		}, []job{{"gordonb", "123456"}, {"gordonb", "abc123"}, {"pablo", "letmein"}}},
+		{"блокировки и ошибки пробуются снова", []result{
+			r("gordonb", "123456", verdictLockout),
+			r("gordonb", "abc123", verdictError),
+			r("pablo", "123456", verdictSkipped),
+		}, pairs},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "lab3.state")
+			first := newAttackState("http://target/login")
+			for _, res := range c.recorded {
+				first.record(res)
+			}
+			if err := first.save(path); err != nil {
+				t.Fatal(err)
+			}
+			state, err := loadAttackState(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Target != first.Target {
+				t.Errorf("адрес %q, ожидался %q", state.Target, first.Target)
+			}
+
+			in := make(chan job)
+			out := make(chan job)
+			go func() {
+				defer close(in)
+				for _, j := range pairs {
+					in <- j
+				}
+			}()
+			skipped := make(chan int, 1)
+			go func() {
+				defer close(out)
+				skipped <- state.filter(context.Background(), in, out)
+			}()
+			var got []job
+			for j := range out {
+				got = append(got, j)
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("пробуются %v, ожидалось %v", got, c.want)
+			}
+			if n := <-skipped; n != len(pairs)-len(c.want) {
+				t.Errorf("пропущено %d, ожидалось %d", n, len(pairs)-len(c.want))
+			}
+		})
+	}
+}
+
+// Повторно записанная пара хранится в состоянии один раз
+func TestAttackStateMarkDoneOnce(t *testing.T) {
+	s := newAttackState("")
+	for i := 0; i < 3; i++ {
+		s.record(result{job: job{"admin", "x"}, verdict: verdictFailure})
+	}
+	if got := s.Done["admin"]; !slices.Equal(got, []string{"x"}) {
+		t.Errorf("проверенные пароли %v, ожидалось [x]", got)
+	}
+}
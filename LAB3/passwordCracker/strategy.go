@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Стратегии атаки
+const (
+	strategyClusterBomb = "clusterbomb" // все пароли для каждого логина по очереди
+	strategyPitchfork   = "pitchfork"   // i-й логин с i-м паролем
+	strategyStuffing    = "stuffing"    // пары логин:пароль из файла
+	strategySpray       = "spray"       // один пароль для всех логинов, затем следующий
+)
+
+// Списки для атаки
+type attackLists struct {
+	users     []string
+	passwords []string            // общий словарь; при потоковом чтении не загружается
+	perUser   map[string][]string // целевые словари логинов из -userlists
+	combos    []job               // пары для credential stuffing; при потоковом чтении не загружаются
+
+	// При потоковом чтении общий словарь и файл пар читаются заново
+	// при каждом проходе, а не хранятся в памяти
+	stream        bool
+	passwordsPath string
+	comboPath     string
+	opts          wordlistOptions
+	reported      bool // итоги чтения словаря уже выведены
+}
+
+// Количество паролей для логина: целевой словарь и общий
+func (l *attackLists) countFor(user string) int {
+	return len(l.perUser[user]) + len(l.passwords)
+}
+
+// i-й пароль для логина: сначала целевой словарь, затем общий
+func (l *attackLists) passwordAt(user string, i int) string {
+	targeted := l.perUser[user]
+	if i < len(targeted) {
+		return targeted[i]
+	}
+	return l.passwords[i-len(targeted)]
+}
+
+// Проход по общему словарю. fn может вернуть errStopWordlist,
+// чтобы закончить проход досрочно.
+func (l *attackLists) eachPassword(fn func(string) error) error {
+	if !l.stream {
+		for _, pass := range l.passwords {
+			if err := fn(pass); err != nil {
+				if errors.Is(err, errStopWordlist) {
+					return nil
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	stats, err := scanWordlist(l.passwordsPath, l.opts, fn)
+	if err == nil {
+		l.report(l.passwordsPath, stats)
+	}
+	return err
+}
+
+// Итоги чтения словаря; при потоковом чтении выводятся после первого прохода
+func (l *attackLists) report(path string, stats wordlistStats) {
+	if !l.reported {
+		l.reported = true
+		fmt.Printf("Словарь %s: %s\n", path, stats)
+	}
+}
+
+// Чтение непустых строк файла без фильтров
+func readLines(path string) ([]string, error) {
+	lines, stats, err := readWordlist(path, wordlistOptions{})
+	if err == nil && stats.TooLong > 0 {
+		err = fmt.Errorf("%s: строк длиннее %d байт: %d", path, defaultMaxLine, stats.TooLong)
+	}
+	return lines, err
+}
+
+// Запись строк в файл, по одной в строке
+func writeLines(path string, lines []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(file, line); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// Имя файла словаря для логина, совпадает с правилом генератора LAB2 -gen-targeted
+func userListFileName(username string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-') {
+			return r
+		}
+		return '_'
+	}, username)
+	return safe + ".txt"
+}
+
+// Загрузка целевых словарей из каталога dir для логинов, у которых они есть.
+// Фильтры словаря применяются и к ним.
+func (l *attackLists) loadUserLists(dir string) error {
+	l.perUser = make(map[string][]string)
+	for _, user := range l.users {
+		targeted, _, err := readWordlist(filepath.Join(dir, userListFileName(user)), l.opts)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		l.perUser[user] = targeted
+	}
+	return nil
+}
+
+// Проход по файлу пар логин:пароль, разделитель — первое двоеточие
+func scanCombos(path string, opts wordlistOptions, fn func(job) error) (wordlistStats, error) {
+	opts.Combo = true
+	n := 0
+	return scanWordlist(path, opts, func(line string) error {
+		n++
+		login, pass, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("%s: пара %d: ожидалось логин:пароль", path, n)
+		}
+		return fn(job{login: login, pass: pass})
+	})
+}
+
+// Формирование заданий по стратегии. Канал jobs закрывает вызывающий.
+// Для распыления между раундами выдерживается пауза sprayDelay.
+func produceJobs(ctx context.Context, jobs chan<- job, strategy string, l *attackLists, sprayDelay time.Duration) error {
+	switch strategy {
+	case strategyClusterBomb:
+		for _, user := range l.users {
+			for _, pass := range l.perUser[user] {
+				if err := sendJob(ctx, jobs, job{login: user, pass: pass}); err != nil {
+					return err
+				}
+			}
+			err := l.eachPassword(func(pass string) error {
+				return sendJob(ctx, jobs, job{login: user, pass: pass})
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+	case strategyPitchfork:
+		// Словарь читается с пустыми словами на месте отброшенных строк,
+		// поэтому вместе со строкой отбрасывается и пара, а остальные
+		// пароли не сдвигаются на чужие логины. Повторяются только пары.
+		i := 0
+		seen := make(map[job]struct{})
+		return l.eachPassword(func(pass string) error {
+			if i >= len(l.users) {
+				return errStopWordlist
+			}
+			j := job{login: l.users[i], pass: pass}
+			i++
+			if pass == "" {
+				return nil
+			}
+			if l.opts.Dedup {
+				if _, dup := seen[j]; dup {
+					return nil
+				}
+				seen[j] = struct{}{}
+			}
+			return sendJob(ctx, jobs, j)
+		})
+
+	case strategyStuffing:
+		if l.stream {
+			stats, err := scanCombos(l.comboPath, l.opts, func(j job) error {
+				return sendJob(ctx, jobs, j)
+			})
+			if err == nil {
+				l.report(l.comboPath, stats)
+			}
+			return err
+		}
+		for _, j := range l.combos {
+			if err := sendJob(ctx, jobs, j); err != nil {
+				return err
+			}
+		}
+
+	case strategySpray:
+		return produceSpray(ctx, jobs, l, sprayDelay)
+
+	default:
+		return fmt.Errorf("неизвестная стратегия %q", strategy)
+	}
+	return nil
+}
+
+// Распыление: один пароль для всех логинов, затем следующий. При потоковом
+// чтении общий словарь читается один раз, поэтому сначала идут раунды
+// по целевым словарям, а затем по общему.
+func produceSpray(ctx context.Context, jobs chan<- job, l *attackLists, sprayDelay time.Duration) error {
+	count := l.countFor
+	if l.stream {
+		count = func(user string) int { return len(l.perUser[user]) }
+	}
+	rounds, done := 0, 0
+	for _, user := range l.users {
+		rounds = max(rounds, count(user))
+	}
+	// Пауза перед каждым раундом, кроме первого
+	pause := func() error {
+		if done++; done == 1 || sprayDelay <= 0 {
+			return nil
+		}
+		fmt.Printf("Раунд %d завершён, пауза %s\n", done-1, sprayDelay)
+		return sleepContext(ctx, sprayDelay)
+	}
+
+	for round := 0; round < rounds; round++ {
+		if err := pause(); err != nil {
+			return err
+		}
+		for _, user := range l.users {
+			if round >= count(user) {
+				continue
+			}
+			if err := sendJob(ctx, jobs, job{login: user, pass: l.passwordAt(user, round)}); err != nil {
+				return err
+			}
+		}
+	}
+	if !l.stream {
+		return nil
+	}
+	return l.eachPassword(func(pass string) error {
+		if err := pause(); err != nil {
+			return err
+		}
+		for _, user := range l.users {
+			if err := sendJob(ctx, jobs, job{login: user, pass: pass}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Сон, прерываемый отменой контекста
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Загрузка списков, нужных стратегии. Фильтры opts применяются к паролям;
+// при stream общий словарь и файл пар не загружаются, а читаются при переборе.
+func loadAttackLists(strategy, usersPath, passwordsPath, comboPath, userLists string, opts wordlistOptions, stream bool) (*attackLists, error) {
+	// Пароль pitchfork сопоставляется логину по номеру строки
+	opts.Aligned = strategy == strategyPitchfork
+	l := &attackLists{stream: stream, passwordsPath: passwordsPath, comboPath: comboPath, opts: opts}
+	var err error
+	if strategy == strategyStuffing {
+		if comboPath == "" {
+			return nil, fmt.Errorf("для стратегии %s нужен файл -combo", strategy)
+		}
+		if stream {
+			// Файл проверяется до начала атаки, а не посреди неё
+			return l, checkWordlist(comboPath)
+		}
+		stats, err := scanCombos(comboPath, opts, func(j job) error {
+			l.combos = append(l.combos, j)
+			return nil
+		})
+		if err == nil {
+			l.report(comboPath, stats)
+		}
+		return l, err
+	}
+
+	if l.users, err = readLines(usersPath); err != nil {
+		return nil, err
+	}
+	if stream {
+		err = checkWordlist(passwordsPath)
+	} else {
+		var stats wordlistStats
+		l.passwords, stats, err = readWordlist(passwordsPath, opts)
+		if err == nil {
+			l.report(passwordsPath, stats)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userLists != "" {
+		if strategy == strategyPitchfork {
+			return nil, fmt.Errorf("целевые словари не используются стратегией %s", strategy)
+		}
+		err = l.loadUserLists(userLists)
+	}
+	return l, err
+}
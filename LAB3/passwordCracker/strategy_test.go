@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Файл в каталоге теста с заданными строками
+func writeTestFile(t *testing.T, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Все задания стратегии
+func collectJobs(t *testing.T, strategy string, l *attackLists) []job {
+	t.Helper()
+	jobs := make(chan job)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		errc <- produceJobs(context.Background(), jobs, strategy, l, 0)
+	}()
+	var all []job
+	for j := range jobs {
+		all = append(all, j)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+// Отброшенный фильтром или повторный пароль pitchfork не сдвигает
+// следующие пароли на чужие логины
+func TestPitchforkKeepsPairsAligned(t *testing.T) {
+	users := writeTestFile(t, "users.txt", "alice", "bob", "carol", "dave", "eve")
+	passwords := writeTestFile(t, "passwords.txt", "pass1", "pass1", "x", "pass4", "pass5")
+	opts := wordlistOptions{Dedup: true, Policy: passwordPolicy{MinLen: 2}}
+	want := []job{{"alice", "pass1"}, {"bob", "pass1"}, {"dave", "pass4"}, {"eve", "pass5"}}
+
+	for _, stream := range []bool{false, true} {
+		l, err := loadAttackLists(strategyPitchfork, users, passwords, "", "", opts, stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := collectJobs(t, strategyPitchfork, l); !slices.Equal(got, want) {
+			t.Errorf("stream=%v: пары %v, ожидалось %v", stream, got, want)
+		}
+	}
+}
+
+// Для остальных стратегий фильтры и пропуск повторов работают как раньше
+func TestClusterBombFiltersPasswords(t *testing.T) {
+	users := writeTestFile(t, "users.txt", "alice")
+	passwords := writeTestFile(t, "passwords.txt", "pass1", "pass1", "x", "pass4")
+	opts := wordlistOptions{Dedup: true, Policy: passwordPolicy{MinLen: 2}}
+	l, err := loadAttackLists(strategyClusterBomb, users, passwords, "", "", opts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []job{{"alice", "pass1"}, {"alice", "pass4"}}
+	if got := collectJobs(t, strategyClusterBomb, l); !slices.Equal(got, want) {
+		t.Errorf("пары %v, ожидалось %v", got, want)
+	}
+}
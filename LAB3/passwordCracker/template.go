@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Метки подстановки в шаблоне запроса
+const (
+	placeholderUser  = "§USER§"
+	placeholderPass  = "§PASS§"
+	placeholderToken = "§TOKEN§"
+	placeholderAuth  = "§AUTHTOKEN§"
+)
+
+// Шаблон запроса попытки входа. Метки §USER§, §PASS§, §TOKEN§
+// и §AUTHTOKEN§ (значение -auth-token) подставляются в URL, заголовки и тело с экранированием по месту:
+// в пути и строке запроса — percent-encoding, в теле — по Content-Type
+// (форма или JSON), в заголовках — как есть.
+type requestTemplate struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"` // может быть относительным к -url
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// Шаблон формы DVWA: GET с параметрами в строке запроса,
+// а на уровне impossible — POST с теми же параметрами в теле
+func dvwaTemplate(security string, withToken bool) *requestTemplate {
+	params := "username=" + placeholderUser + "&password=" + placeholderPass + "&Login=Login"
+	if withToken {
+		params += "&user_token=" + placeholderToken
+	}
+	if security == "impossible" {
+		return &requestTemplate{Method: http.MethodPost, Body: params}
+	}
+	return &requestTemplate{Method: http.MethodGet, URL: "?" + params}
+}
+
+// Загрузка шаблона из JSON-файла
+func loadTemplate(path string) (*requestTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t requestTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if t.Method == "" {
+		t.Method = http.MethodGet
+	}
+	return &t, nil
+}
+
+// В шаблоне есть метка токена, значит перед попыткой нужен свежий токен
+func (t *requestTemplate) usesToken() bool {
+	return t.uses(placeholderToken)
+}
+
+// Метка встречается в URL, теле или заголовках шаблона
+func (t *requestTemplate) uses(placeholder string) bool {
+	if strings.Contains(t.URL, placeholder) || strings.Contains(t.Body, placeholder) {
+		return true
+	}
+	for _, v := range t.Headers {
+		if strings.Contains(v, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// Значения для подстановки
+type credentials struct {
+	user, pass, token string
+	authToken         string
+}
+
+func (c credentials) replace(s string, escape func(string) string) string {
+	return strings.NewReplacer(
+		placeholderUser, escape(c.user),
+		placeholderPass, escape(c.pass),
+		placeholderToken, escape(c.token),
+		placeholderAuth, escape(c.authToken),
+	).Replace(s)
+}
+
+// Экранирование для строки JSON без внешних кавычек
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// Значения в заголовках подставляются как есть, кроме переводов строк
+func headerEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func noEscape(s string) string {
+	return s
+}
+
+// Content-Type шаблона; для тела без явного типа — форма
+func (t *requestTemplate) contentType() string {
+	for k, v := range t.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return v
+		}
+	}
+	if t.Body != "" {
+		return "application/x-www-form-urlencoded"
+	}
+	return ""
+}
+
+func bodyEscaper(contentType string) func(string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return jsonEscape
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return url.QueryEscape
+	default:
+		return noEscape
+	}
+}
+
+// Адрес, на который уходят попытки, без строки запроса: относительный
+// URL шаблона разрешается от base. nil — адрес не разбирается.
+func (t *requestTemplate) target(base *url.URL) *url.URL {
+	rawURL, _, _ := strings.Cut(t.URL, "#")
+	path, _, _ := strings.Cut(rawURL, "?")
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil
+	}
+	return base.ResolveReference(ref)
+}
+
+// Сборка запроса; относительный URL шаблона разрешается от base
+func (t *requestTemplate) newRequest(ctx context.Context, base *url.URL, c credentials) (*http.Request, error) {
+	rawURL, fragment, _ := strings.Cut(t.URL, "#")
+	path, query, hasQuery := strings.Cut(rawURL, "?")
+	rawURL = c.replace(path, url.PathEscape)
+	if hasQuery {
+		rawURL += "?" + c.replace(query, url.QueryEscape)
+	}
+	if fragment != "" {
+		rawURL += "#" + fragment
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	contentType := t.contentType()
+	if t.Body != "" {
+		body = strings.NewReader(c.replace(t.Body, bodyEscaper(contentType)))
+	}
+	req, err := http.NewRequestWithContext(ctx, t.Method, base.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range t.Headers {
+		v = c.replace(v, headerEscape)
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
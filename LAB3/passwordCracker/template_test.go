@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"auth/server"
+)
+
+// Шаблон templates/authsystem.json отправляет токен из -auth-token,
+// без которого authSystem не пускает даже с верным паролем
+func TestAuthSystemTemplate(t *testing.T) {
+	srv := server.New(map[string]string{"admin": "password"})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	tmpl, err := loadTemplate("templates/authsystem.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := url.Parse(ts.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.URL = target.String()
+
+	for _, c := range []struct {
+		name      string
+		authToken string
+		pass      string
+		want      int
+	}{
+		{"верный пароль и токен", srv.Token("admin"), "password", http.StatusOK},
+		{"неверный пароль", srv.Token("admin"), "123456", http.StatusUnauthorized},
+		{"без токена", "", "password", http.StatusUnauthorized},
+	} {
+		sess, err := newSession(sessionConfig{Target: target, Cookie: "session=test", Template: tmpl, AuthToken: c.authToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := sess.attempt(context.Background(), "admin", c.pass)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("%s: status code = %v, ожидалось %v", c.name, resp.StatusCode, c.want)
+		}
+	}
+}
+
+// Значения со спецсимволами доходят до сервера без искажений в строке
+// запроса, пути, теле формы, теле JSON и заголовке
+func TestTemplateEscaping(t *testing.T) {
+	formValues := func(r *http.Request) (string, string, error) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", "", err
+		}
+		v, err := url.ParseQuery(string(data))
+		return v.Get("username"), v.Get("password"), err
+	}
+	templates := []struct {
+		name   string
+		tmpl   requestTemplate
+		decode func(*http.Request) (string, string, error)
+	}{
+		{"строка запроса", requestTemplate{Method: http.MethodGet, URL: "/login?username=§USER§&password=§PASS§#frag"},
+			func(r *http.Request) (string, string, error) {
+				q := r.URL.Query()
+				return q.Get("username"), q.Get("password"), nil
+			}},
+		{"путь", requestTemplate{Method: http.MethodGet, URL: "/users/§USER§/§PASS§"},
+			func(r *http.Request) (string, string, error) {
+				user, pass, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+				return user, pass, nil
+			}},
+		{"форма", requestTemplate{Method: http.MethodPost, URL: "/login", Body: "username=§USER§&password=§PASS§"}, formValues},
+		{"JSON", requestTemplate{
+			Method:  http.MethodPost,
+			URL:     "/api/login",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    `{"username": "§USER§", "password": "§PASS§"}`,
+		}, func(r *http.Request) (string, string, error) {
+			var v struct{ Username, Password string }
+			err := json.NewDecoder(r.Body).Decode(&v)
+			return v.Username, v.Password, err
+		}},
+		{"заголовок", requestTemplate{Method: http.MethodGet, URL: "/", Headers: map[string]string{"X-User": "§USER§", "X-Pass": "§PASS§"}},
+			func(r *http.Request) (string, string, error) {
+				return r.Header.Get("X-User"), r.Header.Get("X-Pass"), nil
+			}},
+	}
+	values := []struct {
+		name       string
+		user, pass string
+	}{
+		{"амперсанд и равно", "a&b=c", "p&password=x"},
+		{"решётка", "user#1", "#pass#"},
+		{"процент", "100%", "%41%zz"},
+		{"пробелы и плюс", "john smith", " a+b "},
+		{"кавычки и обратная косая", `"quoted"`, `it's \"x\"`},
+		{"кириллица", "пользователь", "пароль"},
+	}
+	base, err := url.Parse("http://target.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range templates {
+		for _, v := range values {
+			req, err := tc.tmpl.newRequest(context.Background(), base, credentials{user: v.user, pass: v.pass})
+			if err != nil {
+				t.Errorf("%s, %s: %v", tc.name, v.name, err)
+				continue
+			}
+			user, pass, err := tc.decode(req)
+			if err != nil {
+				t.Errorf("%s, %s: %v", tc.name, v.name, err)
+				continue
+			}
+			if user != v.user || pass != v.pass {
+				t.Errorf("%s, %s: получено %q / %q, ожидалось %q / %q", tc.name, v.name, user, pass, v.user, v.pass)
+			}
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Настройки анализа утечки через время ответа
+type timingConfig struct {
+	User        string  // существующий логин
+	InvalidUser string  // несуществующий логин; пусто — случайный
+	Password    string  // верный пароль User для сравнения префиксов; пусто — не сравнивать
+	Samples     int     // попыток в каждой группе
+	Warmup      int     // первые попытки, которые не учитываются: установка соединения, прогрев кэшей
+	Alpha       float64 // уровень значимости
+}
+
+// Группа однотипных попыток
+type timingGroup struct {
+	Name    string
+	login   string
+	pass    func() string
+	samples []time.Duration
+	Errors  int
+}
+
+// Описательная статистика группы, в миллисекундах
+type timingStats struct {
+	N                  int
+	Min, P25, Median   float64
+	P75, P90, P99, Max float64
+	Mean, StdDev       float64
+}
+
+func describe(samples []time.Duration) timingStats {
+	n := len(samples)
+	if n == 0 {
+		return timingStats{}
+	}
+	s := slices.Clone(samples)
+	slices.Sort(s)
+	// Процентиль по ближайшему рангу
+	pct := func(p float64) float64 {
+		return ms(s[max(int(math.Ceil(p/100*float64(n)))-1, 0)])
+	}
+	var sum float64
+	for _, d := range s {
+		sum += ms(d)
+	}
+	mean := sum / float64(n)
+	var sq float64
+	for _, d := range s {
+		sq += (ms(d) - mean) * (ms(d) - mean)
+	}
+	st := timingStats{
+		N: n, Min: ms(s[0]), P25: pct(25), Median: pct(50),
+		P75: pct(75), P90: pct(90), P99: pct(99), Max: ms(s[n-1]), Mean: mean,
+	}
+	if n > 1 {
+		st.StdDev = math.Sqrt(sq / float64(n-1))
+	}
+	return st
+}
+
+// Сравнение двух групп
+type timingComparison struct {
+	Name       string
+	A, B       string  // названия групп
+	MedianDiff float64 // медиана A минус медиана B, мс
+	U, Z, PMW  float64 // критерий Манна — Уитни
+	T, DF, PT  float64 // t-критерий Уэлча
+	Leak       bool
+}
+
+// Критерий Манна — Уитни с нормальным приближением, поправкой на связки
+// и на непрерывность. Не требует нормальности распределения, поэтому
+// устойчив к редким долгим ответам. Возвращает U для a, z и двустороннее p.
+func mannWhitney(a, b []time.Duration) (u, z, p float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	type sample struct {
+		d     time.Duration
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, d := range a {
+		all = append(all, sample{d, true})
+	}
+	for _, d := range b {
+		all = append(all, sample{d, false})
+	}
+	slices.SortFunc(all, func(x, y sample) int { return cmp.Compare(x.d, y.d) })
+
+	var rankA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].d == all[i].d {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // средний ранг связки, ранги с единицы
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	u = rankA - n1*(n1+1)/2
+	n := n1 + n2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 0, 1
+	}
+	diff := u - n1*n2/2
+	diff -= math.Copysign(math.Min(0.5, math.Abs(diff)), diff)
+	z = diff / sigma
+	return u, z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// t-критерий Уэлча для групп с разной дисперсией. Возвращает t,
+// число степеней свободы и двустороннее p.
+func welchT(a, b timingStats) (t, df, p float64) {
+	if a.N < 2 || b.N < 2 {
+		return 0, 0, 1
+	}
+	va, vb := a.StdDev*a.StdDev/float64(a.N), b.StdDev*b.StdDev/float64(b.N)
+	se := math.Sqrt(va + vb)
+	if se == 0 {
+		return 0, 0, 1
+	}
+	t = (a.Mean - b.Mean) / se
+	df = (va + vb) * (va + vb) / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	return t, df, regIncBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// Регуляризованная неполная бета-функция I_x(a, b) через цепную дробь
+// (метод Лентца)
+func regIncBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	if x > (a+1)/(a+b+2) {
+		return 1 - regIncBeta(1-x, b, a)
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	const tiny = 1e-300
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 300; i++ {
+		m := float64(i / 2)
+		var num float64
+		switch {
+		case i == 0:
+			num = 1
+		case i%2 == 0:
+			num = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		cd := c * d
+		f *= cd
+		if math.Abs(1-cd) < 1e-12 {
+			return front * (f - 1)
+		}
+	}
+	return front * (f - 1)
+}
+
+// Отчёт об анализе
+type timingReport struct {
+	Target      string
+	Started     time.Time
+	Alpha       float64
+	Groups      []*timingGroup
+	Stats       map[string]timingStats
+	Comparisons []timingComparison
+}
+
+func randomHex(n int) string {
+	b := make([]byte, (n+1)/2)
+	rand.Read(b)
+	return hex.EncodeToString(b)[:n]
+}
+
+// Случайная строка длины n, первый символ которой отличается от first
+func randomNotPrefixed(n int, first byte) string {
+	for {
+		if s := randomHex(n); s[0] != first {
+			return s
+		}
+	}
+}
+
+// Серия контролируемых попыток и сравнение времени ответа: существующий
+// и несуществующий логин с неверным паролем, а при известном пароле —
+// неверные пароли с верной и неверной первой половиной. Попытки идут
+// по одной, в случайном порядке групп, чтобы изменение нагрузки на сервер
+// за время замера одинаково сказалось на всех группах.
+func runTimingAnalysis(ctx context.Context, attempt func(context.Context, job) result, target string, cfg timingConfig) (*timingReport, error) {
+	if cfg.Samples < 2 {
+		return nil, fmt.Errorf("нужно не менее 2 попыток в группе")
+	}
+	if cfg.InvalidUser == "" {
+		cfg.InvalidUser = "nouser-" + randomHex(10)
+	}
+	passLen := max(len(cfg.Password), 12)
+	wrong := func() string { return randomHex(passLen) }
+
+	groups := []*timingGroup{
+		{Name: "существующий логин", login: cfg.User, pass: wrong},
+		{Name: "несуществующий логин", login: cfg.InvalidUser, pass: wrong},
+	}
+	if p := cfg.Password; p != "" {
+		if len(p) < 2 {
+			return nil, fmt.Errorf("для сравнения префиксов нужен пароль не короче 2 символов")
+		}
+		half := len(p) / 2
+		groups = append(groups,
+			&timingGroup{Name: "верная половина пароля", login: cfg.User, pass: func() string {
+				return p[:half] + randomNotPrefixed(len(p)-half, p[half])
+			}},
+			&timingGroup{Name: "неверный пароль той же длины", login: cfg.User, pass: func() string {
+				return randomNotPrefixed(len(p), p[0])
+			}},
+		)
+	}
+
+	var schedule []*timingGroup
+	for i := 0; i < cfg.Samples; i++ {
+		schedule = append(schedule, groups...)
+	}
+	mrand.Shuffle(len(schedule), func(i, j int) { schedule[i], schedule[j] = schedule[j], schedule[i] })
+	warm := make([]*timingGroup, 0, cfg.Warmup)
+	for i := 0; i < cfg.Warmup; i++ {
+		warm = append(warm, groups[i%len(groups)])
+	}
+
+	started := time.Now()
+	fmt.Printf("Замер времени ответа: групп %d, попыток в группе %d, всего %d\n",
+		len(groups), cfg.Samples, len(schedule)+len(warm))
+	for i, g := range append(warm, schedule...) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r := attempt(ctx, job{login: g.login, pass: g.pass()})
+		if i < len(warm) {
+			continue
+		}
+		if r.status == 0 || r.elapsed == 0 {
+			g.Errors++
+			continue
+		}
+		if r.verdict == verdictSuccess {
+			return nil, fmt.Errorf("вход выполнен с паролем %q: группа %q должна содержать только неверные пароли", r.pass, g.Name)
+		}
+		g.samples = append(g.samples, r.elapsed)
+		if done := i + 1 - len(warm); done%100 == 0 {
+			fmt.Printf("  выполнено %d из %d\n", done, len(schedule))
+		}
+	}
+
+	rep := &timingReport{Target: target, Started: started, Alpha: cfg.Alpha, Groups: groups, Stats: make(map[string]timingStats)}
+	for _, g := range groups {
+		if len(g.samples) < 2 {
+			return nil, fmt.Errorf("группа %q: получено ответов %d, ошибок %d", g.Name, len(g.samples), g.Errors)
+		}
+		rep.Stats[g.Name] = describe(g.samples)
+	}
+	compare := func(name string, a, b *timingGroup) {
+		sa, sb := rep.Stats[a.Name], rep.Stats[b.Name]
+		c := timingComparison{Name: name, A: a.Name, B: b.Name, MedianDiff: sa.Median - sb.Median}
+		c.U, c.Z, c.PMW = mannWhitney(a.samples, b.samples)
+		c.T, c.DF, c.PT = welchT(sa, sb)
+		c.Leak = c.PMW < cfg.Alpha
+		rep.Comparisons = append(rep.Comparisons, c)
+	}
+	compare("Существование логина", groups[0], groups[1])
+	if len(groups) == 4 {
+		compare("Посимвольное сравнение пароля", groups[2], groups[3])
+	}
+	return rep, nil
+}
+
+// Вывод отчёта в консоль
+func (rep *timingReport) print() {
+	fmt.Println("Время ответа, мс:")
+	fmt.Printf("  %-30s %5s %8s %8s %8s %8s %8s %8s\n", "группа", "n", "мин", "p25", "медиана", "p75", "p90", "p99")
+	for _, g := range rep.Groups {
+		s := rep.Stats[g.Name]
+		fmt.Printf("  %-30s %5d %8.2f %8.2f %8.2f %8.2f %8.2f %8.2f\n", g.Name, s.N, s.Min, s.P25, s.Median, s.P75, s.P90, s.P99)
+	}
+	for _, c := range rep.Comparisons {
+		fmt.Printf("%s: разница медиан %+.2f мс, Манн — Уитни p = %.4g, Уэлч t = %.2f, p = %.4g\n",
+			c.Name, c.MedianDiff, c.PMW, c.T, c.PT)
+		if c.Leak {
+			fmt.Printf("  Время ответа зависит от данных (p < %g): утечка через время ответа, CWE-208\n", rep.Alpha)
+		} else {
+			fmt.Printf("  Значимой разницы нет (p ≥ %g)\n", rep.Alpha)
+		}
+	}
+}
+
+// Отчёт в формате Markdown для приложения к результатам разбора кода
+func (rep *timingReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Анализ времени ответа %s\n\n", rep.Target)
+	fmt.Fprintf(&b, "Дата: %s. Уровень значимости: %g.\n\n", rep.Started.Format(time.RFC3339), rep.Alpha)
+	b.WriteString("Проверяется, зависит ли время ответа на попытку входа от того, существует ли логин " +
+		"и совпадает ли начало пароля. Такая зависимость — уязвимость " +
+		"[CWE-208: Observable Timing Discrepancy](https://cwe.mitre.org/data/definitions/208.html): " +
+		"по времени ответа можно перебрать существующие логины или подобрать пароль по символу.\n\n")
+
+	b.WriteString("## Время ответа, мс\n\n| Группа | n | Ошибок | Мин | p25 | Медиана | p75 | p90 | p99 | Макс | Среднее | СКО |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+	for _, g := range rep.Groups {
+		s := rep.Stats[g.Name]
+		fmt.Fprintf(&b, "| %s | %d | %d | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f |\n",
+			g.Name, s.N, g.Errors, s.Min, s.P25, s.Median, s.P75, s.P90, s.P99, s.Max, s.Mean, s.StdDev)
+	}
+
+	b.WriteString("\n## Статистические критерии\n\n| Проверка | Разница медиан, мс | U | z | p (Манн — Уитни) | t | df | p (Уэлч) | Вывод |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|---:|---|\n")
+	for _, c := range rep.Comparisons {
+		verdict := "разницы нет"
+		if c.Leak {
+			verdict = "**утечка, CWE-208**"
+		}
+		fmt.Fprintf(&b, "| %s (%s / %s) | %+.2f | %.0f | %.2f | %.3g | %.2f | %.1f | %.3g | %s |\n",
+			c.Name, c.A, c.B, c.MedianDiff, c.U, c.Z, c.PMW, c.T, c.DF, c.PT, verdict)
+	}
+	b.WriteString("\nВывод делается по критерию Манна — Уитни: он не предполагает нормального " +
+		"распределения и устойчив к редким долгим ответам. t-критерий Уэлча приведён для сравнения средних.\n")
+
+	if rep.leaks() {
+		b.WriteString("\n## Рекомендации\n\n" +
+			"- Выполнять одинаковую работу для существующих и несуществующих логинов: " +
+			"для несуществующего сравнивать пароль с заранее вычисленным фиктивным хэшем.\n" +
+			"- Сравнивать секреты за постоянное время (`crypto/subtle.ConstantTimeCompare`, `hash_equals`).\n" +
+			"- Хранить пароли в виде медленного хэша (bcrypt, Argon2) и сравнивать хэши, а не пароли.\n")
+	}
+	return b.String()
+}
+
+func (rep *timingReport) write(path string) error {
+	return os.WriteFile(path, []byte(rep.markdown()), 0o644)
+}
+
+// Есть ли утечка хотя бы в одной проверке
+func (rep *timingReport) leaks() bool {
+	for _, c := range rep.Comparisons {
+		if c.Leak {
+			return true
+		}
+	}
+	return false
+}
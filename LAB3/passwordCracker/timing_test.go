@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cracker/mockdvwa"
+)
+
+// /login с утечкой через время ответа: пароль существующего пользователя
+// проверяется дольше на delay, как при сравнении медленного хэша только
+// для найденных в базе логинов
+func startTimingServer(users map[string]string, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pass, ok := users[r.FormValue("username")]
+		if ok {
+			time.Sleep(delay)
+		}
+		if !ok || r.FormValue("password") != pass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestDescribe(t *testing.T) {
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		samples[len(samples)-1-i] = time.Duration(i+1) * time.Millisecond
+	}
+	st := describe(samples)
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"min", st.Min, 1},
+		{"p25", st.P25, 25},
+		{"median", st.Median, 50},
+		{"p90", st.P90, 90},
+		{"p99", st.P99, 99},
+		{"max", st.Max, 100},
+		{"mean", st.Mean, 50.5},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, ожидалось %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+// Двустороннее p t-распределения через неполную бета-функцию
+// сверяется с табличными значениями
+func TestRegIncBeta(t *testing.T) {
+	for _, c := range []struct {
+		t, df, p float64
+	}{
+		{0, 10, 1},
+		{2, 10, 0.0734},
+		{2.228, 10, 0.05},
+		{2.576, 1e6, 0.01},
+		{1.96, 30, 0.0593},
+	} {
+		p := regIncBeta(c.df/(c.df+c.t*c.t), c.df/2, 0.5)
+		if math.Abs(p-c.p) > 5e-4 {
+			t.Errorf("t = %v, df = %v: p = %.4f, ожидалось %.4f", c.t, c.df, p, c.p)
+		}
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	series := func(from, n int) []time.Duration {
+		s := make([]time.Duration, n)
+		for i := range s {
+			s[i] = time.Duration(from+i) * time.Millisecond
+		}
+		return s
+	}
+	for _, c := range []struct {
+		name  string
+		a, b  []time.Duration
+		leak  bool
+		wantU float64
+	}{
+		{"одинаковые группы", series(1, 20), series(1, 20), false, 200},
+		{"пересекающиеся группы", series(1, 20), series(2, 20), false, 180.5},
+		{"группы не пересекаются", series(100, 20), series(1, 20), true, 400},
+	} {
+		u, _, p := mannWhitney(c.a, c.b)
+		if u != c.wantU {
+			t.Errorf("%s: U = %v, ожидалось %v", c.name, u, c.wantU)
+		}
+		if leak := p < 0.001; leak != c.leak {
+			t.Errorf("%s: p = %.3g", c.name, p)
+		}
+	}
+}
+
+// Задержка только для существующих логинов должна обнаруживаться,
+// а при одинаковой обработке утечки быть не должно
+func TestTimingAnalysis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("серия из сотен попыток")
+	}
+	for _, c := range []struct {
+		name  string
+		delay time.Duration
+		leak  bool
+	}{
+		{"утечка через время ответа", 3 * time.Millisecond, true},
+		{"нет утечки при одинаковой обработке", 0, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			srv := startTimingServer(mockdvwa.DefaultUsers, c.delay)
+			defer srv.Close()
+			u, err := url.Parse(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			auth, err := newAuthenticator(protocolAuthSys, protocolConfig{Target: u})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cls, err := newClassifier(protocolRules(protocolAuthSys, ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rep, err := runTimingAnalysis(context.Background(), loginAttempt(auth, cls), srv.URL, timingConfig{
+				User: "admin", Password: mockdvwa.DefaultUsers["admin"], Samples: 40, Warmup: 5, Alpha: 0.001,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rep.Comparisons[0].Leak; got != c.leak {
+				t.Errorf("утечка обнаружена: %v, ожидалось %v (p = %.3g)", got, c.leak, rep.Comparisons[0].PMW)
+			}
+			if rep.Comparisons[1].Leak {
+				t.Errorf("обнаружена утечка при сравнении пароля, которой нет (p = %.3g)", rep.Comparisons[1].PMW)
+			}
+		})
+	}
+}
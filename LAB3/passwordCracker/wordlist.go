@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Кодировки словарей
+const (
+	encodingAuto   = "auto"   // строка в UTF-8 остаётся как есть, иначе читается как Windows-1251
+	encodingUTF8   = "utf-8"  // строки не в UTF-8 пропускаются
+	encodingCP1251 = "cp1251" // все строки перекодируются из Windows-1251
+)
+
+// Предел длины строки по умолчанию, как у bufio.Scanner
+const defaultMaxLine = bufio.MaxScanTokenSize
+
+// Остановка чтения словаря без ошибки
+var errStopWordlist = errors.New("чтение словаря остановлено")
+
+// Требования к паролям, как в парольной политике цели
+type passwordPolicy struct {
+	MinLen  int    // в символах, 0 — без ограничения
+	MaxLen  int    // в символах, 0 — без ограничения
+	Require string // классы символов, которые должны встретиться: l, u, d, s
+	Allowed string // классы, из которых может состоять пароль; пусто — любые символы
+}
+
+// Классы символов в обозначениях масок LAB2: ?l строчные, ?u заглавные,
+// ?d цифры, ?s спецсимволы. Буквы и цифры — любого алфавита.
+var charClasses = map[byte]func(rune) bool{
+	'l': unicode.IsLower,
+	'u': unicode.IsUpper,
+	'd': unicode.IsDigit,
+	's': func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' ' },
+}
+
+// Разбор классов вида ?l?d; ?a — все классы
+func parseCharClasses(s string) (string, error) {
+	var classes []byte
+	for rest := s; rest != ""; rest = rest[2:] {
+		if len(rest) < 2 || rest[0] != '?' {
+			return "", fmt.Errorf("классы символов %q: ожидалось ?l, ?u, ?d, ?s или ?a", s)
+		}
+		switch c := rest[1]; {
+		case c == 'a':
+			classes = append(classes, "luds"...)
+		case charClasses[c] != nil:
+			classes = append(classes, c)
+		default:
+			return "", fmt.Errorf("классы символов %q: неизвестный класс ?%c", s, c)
+		}
+	}
+	return string(classes), nil
+}
+
+func (p passwordPolicy) allows(word string) bool {
+	n := utf8.RuneCountInString(word)
+	if n < p.MinLen || p.MaxLen > 0 && n > p.MaxLen {
+		return false
+	}
+	var seen [256]bool
+	for _, r := range word {
+		ok := p.Allowed == ""
+		for i := 0; i < len(p.Allowed); i++ {
+			if charClasses[p.Allowed[i]](r) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+		for i := 0; i < len(p.Require); i++ {
+			if charClasses[p.Require[i]](r) {
+				seen[p.Require[i]] = true
+			}
+		}
+	}
+	for i := 0; i < len(p.Require); i++ {
+		if !seen[p.Require[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// Настройки чтения словаря
+type wordlistOptions struct {
+	MaxLine  int    // предел длины строки в байтах, 0 — defaultMaxLine; длинные строки пропускаются
+	MaxWords int    // сколько слов взять из файла, 0 — все
+	Dedup    bool   // пропускать повторы
+	Encoding string // encodingAuto, encodingUTF8 или encodingCP1251; пусто — encodingAuto
+	Policy   passwordPolicy
+	Combo    bool // строки логин:пароль, политика проверяется по паролю
+	// Вместо отброшенной строки передаётся пустое слово, чтобы номер слова
+	// совпадал с номером непустой строки файла; повторы при этом не отбрасываются.
+	// Нужно для pitchfork, где пароль сопоставляется логину по номеру строки.
+	Aligned bool
+}
+
+// Итоги чтения словаря
+type wordlistStats struct {
+	Lines      int // непустых строк
+	Words      int // слов передано в перебор
+	Duplicates int
+	Filtered   int // не подошли под политику
+	TooLong    int
+	Invalid    int // не в UTF-8 при -encoding utf-8
+	Recoded    int // перекодировано из Windows-1251
+}
+
+func (s wordlistStats) String() string {
+	parts := []string{fmt.Sprintf("строк %d, слов %d", s.Lines, s.Words)}
+	for _, p := range []struct {
+		name string
+		n    int
+	}{
+		{"повторов", s.Duplicates},
+		{"не подходят под политику", s.Filtered},
+		{"слишком длинных", s.TooLong},
+		{"не в UTF-8", s.Invalid},
+		{"перекодировано из Windows-1251", s.Recoded},
+	} {
+		if p.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", p.name, p.n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Открытие словаря; сжатый gzip распознаётся по сигнатуре, а не по расширению
+func openWordlist(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(file)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{zr, file}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, file}, nil
+}
+
+// Проверка, что словарь открывается и распаковывается
+func checkWordlist(path string) error {
+	file, err := openWordlist(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Чтение строки без перевода строки. Строка длиннее max дочитывается
+// до конца, но не сохраняется, и возвращается tooLong.
+func readLine(r *bufio.Reader, max int) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > max+2 { // с запасом на \r\n
+				tooLong, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > max {
+			tooLong, line = true, nil
+		}
+		if err == io.EOF && (len(line) > 0 || tooLong) {
+			err = nil
+		}
+		return line, tooLong, err
+	}
+}
+
+// Потоковое чтение словаря: fn вызывается для каждого слова, прошедшего
+// фильтры. Файл в память целиком не загружается; для пропуска повторов
+// хранятся только 64-битные хэши слов. Если fn возвращает errStopWordlist,
+// чтение заканчивается без ошибки. При opts.Aligned fn вызывается и для
+// отброшенных строк, с пустым словом.
+func scanWordlist(path string, opts wordlistOptions, fn func(string) error) (wordlistStats, error) {
+	var stats wordlistStats
+	file, err := openWordlist(path)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+	if opts.MaxLine <= 0 {
+		opts.MaxLine = defaultMaxLine
+	}
+
+	var seed maphash.Seed
+	var seen map[uint64]struct{}
+	if opts.Dedup && !opts.Aligned {
+		seed, seen = maphash.MakeSeed(), make(map[uint64]struct{})
+	}
+	r := bufio.NewReaderSize(file, 64<<10)
+	for first := true; ; first = false {
+		raw, tooLong, err := readLine(r, opts.MaxLine)
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, fmt.Errorf("%s: %w", path, err)
+		}
+		if first {
+			raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+		}
+		if len(raw) == 0 && !tooLong {
+			continue
+		}
+		stats.Lines++
+
+		word, ok := filterWord(raw, tooLong, opts, &stats)
+		if !ok {
+			word = ""
+		}
+		if ok && seen != nil {
+			h := maphash.String(seed, word)
+			if _, dup := seen[h]; dup {
+				stats.Duplicates++
+				continue
+			}
+			seen[h] = struct{}{}
+		}
+		if !ok && !opts.Aligned {
+			continue
+		}
+		if err := fn(word); err != nil {
+			if errors.Is(err, errStopWordlist) {
+				return stats, nil
+			}
+			return stats, err
+		}
+		if !ok {
+			continue
+		}
+		if stats.Words++; opts.MaxWords > 0 && stats.Words >= opts.MaxWords {
+			return stats, nil
+		}
+	}
+}
+
+// Перекодировка строки словаря и проверка по политике.
+// false — строка отброшена, причина учтена в stats.
+func filterWord(raw []byte, tooLong bool, opts wordlistOptions, stats *wordlistStats) (string, bool) {
+	if tooLong {
+		stats.TooLong++
+		return "", false
+	}
+	var word string
+	switch {
+	case opts.Encoding == encodingCP1251, opts.Encoding != encodingUTF8 && !utf8.Valid(raw):
+		word = decodeCP1251(raw)
+		stats.Recoded++
+	case !utf8.Valid(raw):
+		stats.Invalid++
+		return "", false
+	default:
+		word = string(raw)
+	}
+
+	pass := word
+	if opts.Combo {
+		_, pass, _ = strings.Cut(word, ":")
+	}
+	if !opts.Policy.allows(pass) {
+		stats.Filtered++
+		return "", false
+	}
+	return word, true
+}
+
+// Чтение словаря целиком
+func readWordlist(path string, opts wordlistOptions) ([]string, wordlistStats, error) {
+	var words []string
+	stats, err := scanWordlist(path, opts, func(w string) error {
+		words = append(words, w)
+		return nil
+	})
+	return words, stats, err
+}
+
+// Windows-1251: 0xC0–0xFF — А–я подряд, 0x80–0xBF — по таблице,
+// 0x98 в кодировке не определён
+var cp1251High = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', utf8.RuneError, '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+func decodeCP1251(b []byte) string {
+	var s strings.Builder
+	s.Grow(len(b) * 2)
+	for _, c := range b {
+		switch {
+		case c < 0x80:
+			s.WriteByte(c)
+		case c < 0xc0:
+			s.WriteRune(cp1251High[c-0x80])
+		default:
+			s.WriteRune(rune(c) - 0xc0 + 'А')
+		}
+	}
+	return s.String()
+}